@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/services"
+)
+
+// handleBackfillSummaries 根据测例重新统计所有测试运行的测例数量和总耗时
+// 迁移已经为已有的测试运行生成统计，用于统计与测例不一致时修正，可以重复执行
+func handleBackfillSummaries() {
+	fmt.Println("Backfilling test run summaries")
+	done, err := services.BackfillTestRunSummaries(nil, *batchSize, func(done int) {
+		fmt.Printf("  %d test runs updated\n", done)
+	})
+	if err != nil {
+		log.Fatalf("Failed to backfill test run summaries after %d test runs: %v", done, err)
+	}
+	fmt.Printf("\nDone: %d test runs updated\n", done)
+}
+
+// handleBackfillTestCaseNames 按当前的拆分规则重新拆分已有测例的 suite、test_name 和 param
+// 用于拆分升级前上传的测例，或在拆分规则变化后修正已有测例，可以重复执行
+func handleBackfillTestCaseNames() {
+	fmt.Println("Backfilling test case names")
+	updated, err := services.BackfillTestCaseNames(nil, *batchSize, func(scanned, updated int) {
+		fmt.Printf("  %d test cases scanned, %d updated\n", scanned, updated)
+	})
+	if err != nil {
+		log.Fatalf("Failed to backfill test case names after %d test cases: %v", updated, err)
+	}
+	fmt.Printf("\nDone: %d test cases updated\n", updated)
+}
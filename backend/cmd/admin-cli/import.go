@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/dragonos/dragonos-ci-dashboard/internal/services"
+)
+
+// importFile 历史数据文件格式，与 POST /test-runs 的请求体相同，另外需要指定原始时间
+// created_at 和 started_at 必须指定一个；没有 completed_at 时使用开始时间加上所有测例的耗时
+type importFile struct {
+	ProjectID   *uint64               `json:"project_id"`
+	BranchName  string                `json:"branch_name"`
+	CommitID    string                `json:"commit_id"`
+	TestType    string                `json:"test_type"`
+	Status      string                `json:"status"`
+	Metadata    map[string]string     `json:"metadata"`
+	Commit      *services.CommitInput `json:"commit"`
+	TestCases   []importTestCase      `json:"test_cases"`
+	CreatedAt   *time.Time            `json:"created_at"`
+	StartedAt   *time.Time            `json:"started_at"`
+	CompletedAt *time.Time            `json:"completed_at"`
+
+	PRNumber     *uint32 `json:"pr_number"`
+	BaseCommitID string  `json:"base_commit_id"`
+}
+
+// importTestCase 历史数据文件中的测例
+type importTestCase struct {
+	Name       string                  `json:"name"`
+	Status     string                  `json:"status"`
+	DurationMs uint32                  `json:"duration_ms"`
+	ErrorLog   string                  `json:"error_log"`
+	DebugLog   string                  `json:"debug_log"`
+	Attempts   []importTestCaseAttempt `json:"attempts"`
+}
+
+// importTestCaseAttempt 历史数据文件中测例的单次尝试
+type importTestCaseAttempt struct {
+	Status     string `json:"status"`
+	DurationMs uint32 `json:"duration_ms"`
+	ErrorLog   string `json:"error_log"`
+	DebugLog   string `json:"debug_log"`
+}
+
+// importResult 单个文件的导入结果
+type importResult string
+
+const (
+	importResultImported importResult = "imported"
+	importResultSkipped  importResult = "skipped"
+	importResultWould    importResult = "would-import"
+	importResultFailed   importResult = "failed"
+)
+
+// handleImport 导入目录下的所有历史数据文件（*.json）
+// 每个文件使用基于提交、测试类型和开始时间的幂等键导入，中断后重新执行或文件被移动后会跳过已导入的文件
+func handleImport() {
+	if *importDir == "" {
+		log.Fatal("Directory is required for import action (-dir)")
+	}
+
+	var files []string
+	err := filepath.WalkDir(*importDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".json") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to walk directory: %v", err)
+	}
+
+	if *dryRun {
+		fmt.Printf("Dry run: validating %d files in %s, nothing will be written\n", len(files), *importDir)
+	} else {
+		fmt.Printf("Importing %d files from %s\n", len(files), *importDir)
+	}
+
+	counts := make(map[importResult]int)
+	for _, path := range files {
+		result, detail := importOne(path)
+		counts[result]++
+		fmt.Printf("%-12s %s %s\n", result, path, detail)
+	}
+
+	fmt.Printf("\nDone: %d imported, %d would import, %d skipped (already imported), %d failed\n",
+		counts[importResultImported], counts[importResultWould], counts[importResultSkipped], counts[importResultFailed])
+	if counts[importResultFailed] > 0 {
+		os.Exit(1)
+	}
+}
+
+// importOne 校验并导入单个文件，返回结果和说明
+func importOne(path string) (importResult, string) {
+	input, err := loadImportFile(path)
+	if err != nil {
+		return importResultFailed, err.Error()
+	}
+
+	if *dryRun {
+		if err := services.CheckTestRunInput(nil, input); err != nil {
+			return importResultFailed, err.Error()
+		}
+		hash, err := services.HashPayload(*input)
+		if err != nil {
+			return importResultFailed, err.Error()
+		}
+		existing, err := services.FindIdempotentTestRun(nil, input.ProjectID, input.IdempotencyKey, hash)
+		if err != nil {
+			return importResultFailed, err.Error()
+		}
+		if existing != nil {
+			return importResultSkipped, fmt.Sprintf("test_run_id=%d", existing.ID)
+		}
+		return importResultWould, fmt.Sprintf("test_cases=%d started_at=%s", len(input.TestCases), input.StartedAt.Format(time.RFC3339))
+	}
+
+	testRun, replayed, err := services.IngestTestRun(nil, *input)
+	if err != nil {
+		return importResultFailed, err.Error()
+	}
+	if replayed {
+		return importResultSkipped, fmt.Sprintf("test_run_id=%d", testRun.ID)
+	}
+	return importResultImported, fmt.Sprintf("test_run_id=%d status=%s test_cases=%d", testRun.ID, testRun.Status, len(input.TestCases))
+}
+
+// loadImportFile 读取历史数据文件并转换为测试运行上传数据
+// 只导入已完成的测试运行，运行中或没有测例的文件校验失败
+func loadImportFile(path string) (*services.TestRunInput, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file importFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if file.BranchName == "" || file.CommitID == "" || file.TestType == "" {
+		return nil, errors.New("branch_name, commit_id and test_type are required")
+	}
+	if file.StartedAt == nil && file.CreatedAt == nil {
+		return nil, errors.New("started_at or created_at is required")
+	}
+	if file.Status == string(models.TestRunStatusRunning) {
+		return nil, errors.New("running test runs cannot be imported")
+	}
+	if len(file.TestCases) == 0 {
+		return nil, errors.New("test runs without test cases cannot be imported")
+	}
+
+	projectID := services.DefaultProjectID
+	if file.ProjectID != nil {
+		projectID = *file.ProjectID
+	}
+	if _, err := services.GetProjectByID(nil, projectID); err != nil {
+		return nil, fmt.Errorf("project %d not found", projectID)
+	}
+
+	input := &services.TestRunInput{
+		ProjectID:    projectID,
+		BranchName:   file.BranchName,
+		CommitID:     file.CommitID,
+		TestType:     file.TestType,
+		Status:       file.Status,
+		Metadata:     file.Metadata,
+		Commit:       file.Commit,
+		PRNumber:     file.PRNumber,
+		BaseCommitID: file.BaseCommitID,
+	}
+	var totalDuration time.Duration
+	for _, tc := range file.TestCases {
+		caseInput := services.TestCaseInput{
+			Name:       tc.Name,
+			Status:     models.TestCaseStatus(tc.Status),
+			DurationMs: tc.DurationMs,
+			ErrorLog:   tc.ErrorLog,
+			DebugLog:   tc.DebugLog,
+		}
+		for _, a := range tc.Attempts {
+			caseInput.Attempts = append(caseInput.Attempts, services.TestCaseAttemptInput{
+				Status:     models.TestCaseStatus(a.Status),
+				DurationMs: a.DurationMs,
+				ErrorLog:   a.ErrorLog,
+				DebugLog:   a.DebugLog,
+			})
+			if tc.DurationMs == 0 {
+				totalDuration += time.Duration(a.DurationMs) * time.Millisecond
+			}
+		}
+		totalDuration += time.Duration(tc.DurationMs) * time.Millisecond
+		input.TestCases = append(input.TestCases, caseInput)
+	}
+
+	// 原始时间：started_at > created_at
+	startedAt := *file.CreatedAt
+	if file.StartedAt != nil {
+		startedAt = *file.StartedAt
+	}
+	input.StartedAt = &startedAt
+
+	completedAt := startedAt.Add(totalDuration)
+	if file.CompletedAt != nil {
+		completedAt = *file.CompletedAt
+	}
+	input.CompletedAt = &completedAt
+
+	input.IdempotencyKey = importIdempotencyKey(input.CommitID, input.TestType, startedAt)
+	return input, nil
+}
+
+// importIdempotencyKey 根据测试运行的提交、测试类型和开始时间生成幂等键
+// 同一次测试运行的文件无论放在哪里都只导入一次
+func importIdempotencyKey(commitID, testType string, startedAt time.Time) string {
+	key := fmt.Sprintf("import:%s:%s:%s", commitID, testType, startedAt.UTC().Format(time.RFC3339Nano))
+	if len(key) > services.MaxIdempotencyKeyLength {
+		sum := sha256.Sum256([]byte(key))
+		key = "import:" + hex.EncodeToString(sum[:])
+	}
+	return key
+}
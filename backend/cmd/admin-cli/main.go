@@ -16,11 +16,14 @@ import (
 )
 
 var (
-	action      = flag.String("action", "", "操作类型: create, update-password, update-role")
+	action      = flag.String("action", "", "操作类型: create, update-password, update-role, import, backfill-summaries, backfill-test-case-names")
 	username    = flag.String("username", "", "用户名")
 	password    = flag.String("password", "", "密码（如果不提供，将提示输入）")
 	role        = flag.String("role", "admin", "角色: admin 或 user")
 	interactive = flag.Bool("interactive", false, "交互式模式")
+	importDir   = flag.String("dir", "", "导入历史数据的目录（import 操作）")
+	dryRun      = flag.Bool("dry-run", false, "只校验不写入（import 操作）")
+	batchSize   = flag.Int("batch-size", 500, "每批处理的记录数量（backfill-summaries、backfill-test-case-names 操作）")
 )
 
 func main() {
@@ -52,6 +55,9 @@ func main() {
 		fmt.Println("  admin-cli -action=create -username=admin -password=secret123")
 		fmt.Println("  admin-cli -action=update-password -username=admin")
 		fmt.Println("  admin-cli -action=update-role -username=admin -role=user")
+		fmt.Println("  admin-cli -action=import -dir=./history -dry-run")
+		fmt.Println("  admin-cli -action=backfill-summaries")
+		fmt.Println("  admin-cli -action=backfill-test-case-names")
 		fmt.Println("  admin-cli -interactive")
 		os.Exit(1)
 	}
@@ -63,8 +69,14 @@ func main() {
 		handleUpdatePassword()
 	case "update-role":
 		handleUpdateRole()
+	case "import":
+		handleImport()
+	case "backfill-summaries":
+		handleBackfillSummaries()
+	case "backfill-test-case-names":
+		handleBackfillTestCaseNames()
 	default:
-		log.Fatalf("Unknown action: %s. Use: create, update-password, update-role", *action)
+		log.Fatalf("Unknown action: %s. Use: create, update-password, update-role, import, backfill-summaries, backfill-test-case-names", *action)
 	}
 }
 
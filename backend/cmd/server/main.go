@@ -13,6 +13,7 @@ import (
 	"github.com/dragonos/dragonos-ci-dashboard/internal/api"
 	"github.com/dragonos/dragonos-ci-dashboard/internal/config"
 	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/dragonos/dragonos-ci-dashboard/internal/services"
 	"github.com/dragonos/dragonos-ci-dashboard/pkg/logger"
 )
 
@@ -34,6 +35,20 @@ func main() {
 	}
 	defer models.CloseDatabase()
 
+	// 启动超时运行清理任务
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	defer stopReaper()
+	if interval := config.AppConfig.Reaper.IntervalSeconds; interval > 0 {
+		go services.RunStaleRunReaper(reaperCtx, time.Duration(interval)*time.Second)
+	}
+
+	// 启动分析数据更新任务
+	analyticsCtx, stopAnalytics := context.WithCancel(context.Background())
+	defer stopAnalytics()
+	if interval := config.AppConfig.Analytics.IntervalSeconds; interval > 0 {
+		go services.RunAnalyticsWorker(analyticsCtx, time.Duration(interval)*time.Second)
+	}
+
 	// 设置路由
 	router := api.SetupRouter()
 
@@ -58,6 +73,8 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopReaper()
+	stopAnalytics()
 
 	// 优雅关闭，等待5秒
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
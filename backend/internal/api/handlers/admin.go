@@ -323,6 +323,7 @@ func CreateProject(c *gin.Context) {
 	var req struct {
 		Name        string `json:"name" binding:"required"`
 		Description string `json:"description"`
+		services.ProjectSettings
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -330,7 +331,7 @@ func CreateProject(c *gin.Context) {
 		return
 	}
 
-	project, err := services.CreateProject(c, req.Name, req.Description)
+	project, err := services.CreateProject(c, req.Name, req.Description, req.ProjectSettings)
 	if err != nil {
 		if errors.Is(err, services.ErrProjectExists) {
 			response.BadRequest(c, err.Error())
@@ -355,6 +356,7 @@ func UpdateProject(c *gin.Context) {
 	var req struct {
 		Name        string `json:"name" binding:"required"`
 		Description string `json:"description"`
+		services.ProjectSettings
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -362,7 +364,7 @@ func UpdateProject(c *gin.Context) {
 		return
 	}
 
-	project, err := services.UpdateProject(c, id, req.Name, req.Description)
+	project, err := services.UpdateProject(c, id, req.Name, req.Description, req.ProjectSettings)
 	if err != nil {
 		if errors.Is(err, services.ErrProjectExists) {
 			response.BadRequest(c, err.Error())
@@ -478,6 +480,7 @@ func GetTestRunsAdmin(c *gin.Context) {
 	if status := c.Query("status"); status != "" {
 		params.Status = status
 	}
+	params.Metadata = metadataQuery(c)
 	if page := c.Query("page"); page != "" {
 		if p, err := strconv.Atoi(page); err == nil && p > 0 {
 			params.Page = p
@@ -495,6 +498,16 @@ func GetTestRunsAdmin(c *gin.Context) {
 	// 管理员接口包含私有记录
 	testRuns, total, err := services.QueryTestRuns(c, params, true)
 	if err != nil {
+		if errors.Is(err, services.ErrTestTypeNotFound) {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_runs_admin unknown_test_type test_type=%s", params.TestType)
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvalidMetadataFilter) {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_runs_admin invalid_metadata_filter error=%s", err.Error())
+			response.BadRequest(c, err.Error())
+			return
+		}
 		logger.LogError(c, logger.ModuleHandler, err, "get_test_runs_admin failed")
 		response.InternalServerError(c, "Failed to query test runs")
 		return
@@ -570,3 +583,107 @@ func UpdateSystemConfig(c *gin.Context) {
 		"message": "Config updated successfully",
 	})
 }
+
+// GetTestTypesAdmin 获取测试类型列表（管理员接口）
+func GetTestTypesAdmin(c *gin.Context) {
+	testTypes, err := services.ListTestTypes(c)
+	if err != nil {
+		response.InternalServerError(c, "Failed to get test types")
+		return
+	}
+
+	response.Success(c, testTypes)
+}
+
+// CreateTestType 注册测试类型
+func CreateTestType(c *gin.Context) {
+	var req struct {
+		Name        string   `json:"name" binding:"required,max=50"`
+		DisplayName string   `json:"display_name" binding:"required,max=255"`
+		Description string   `json:"description"`
+		ProjectIDs  []uint64 `json:"allowed_project_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	testType, err := services.CreateTestType(c, req.Name, req.DisplayName, req.Description, req.ProjectIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrTestTypeExists) || errors.Is(err, services.ErrProjectNotFound) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "create_test_type failed name=%s", req.Name)
+		response.InternalServerError(c, "Failed to create test type")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "create_test_type success test_type_id=%d name=%s", testType.ID, testType.Name)
+	response.Success(c, testType)
+}
+
+// UpdateTestType 更新测试类型
+func UpdateTestType(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid test type ID")
+		return
+	}
+
+	var req struct {
+		DisplayName string   `json:"display_name" binding:"required,max=255"`
+		Description string   `json:"description"`
+		ProjectIDs  []uint64 `json:"allowed_project_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	testType, err := services.UpdateTestType(c, id, req.DisplayName, req.Description, req.ProjectIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrTestTypeNotFound) {
+			response.NotFound(c, "Test type not found")
+			return
+		}
+		if errors.Is(err, services.ErrProjectNotFound) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "update_test_type failed test_type_id=%d", id)
+		response.InternalServerError(c, "Failed to update test type")
+		return
+	}
+
+	response.Success(c, testType)
+}
+
+// DeleteTestType 删除测试类型
+func DeleteTestType(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "Invalid test type ID")
+		return
+	}
+
+	if err := services.DeleteTestType(c, id); err != nil {
+		if errors.Is(err, services.ErrTestTypeNotFound) {
+			response.NotFound(c, "Test type not found")
+			return
+		}
+		if errors.Is(err, services.ErrTestTypeInUse) {
+			response.Conflict(c, err.Error())
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "delete_test_type failed test_type_id=%d", id)
+		response.InternalServerError(c, "Failed to delete test type")
+		return
+	}
+
+	response.Success(c, nil)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/services"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/logger"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetCommitBySHA 获取提交信息及该提交的公开测试运行（公开接口）
+// sha 可以是完整的提交哈希，也可以是唯一的前缀（至少7位）
+func GetCommitBySHA(c *gin.Context) {
+	sha := c.Param("sha")
+	logger.LogInfo(c, logger.ModuleHandler, "get_commit_by_sha sha=%s", sha)
+
+	commit, err := services.GetCommitBySHA(c, sha)
+	if err != nil {
+		if errors.Is(err, services.ErrCommitNotFound) {
+			logger.LogWarn(c, logger.ModuleHandler, "get_commit_by_sha commit_not_found sha=%s", sha)
+			response.NotFound(c, "Commit not found")
+			return
+		}
+		if errors.Is(err, services.ErrAmbiguousCommit) {
+			logger.LogWarn(c, logger.ModuleHandler, "get_commit_by_sha ambiguous_commit sha=%s", sha)
+			response.BadRequest(c, err.Error())
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "get_commit_by_sha failed sha=%s", sha)
+		response.InternalServerError(c, "Failed to get commit")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_commit_by_sha success sha=%s test_runs_count=%d", commit.SHA, len(commit.TestRuns))
+	response.Success(c, commit)
+}
+
+// SearchCommits 按作者或提交标题搜索提交（公开接口）
+func SearchCommits(c *gin.Context) {
+	params := services.CommitQueryParams{
+		Author:   c.Query("author"),
+		Query:    c.Query("q"),
+		Page:     1,
+		PageSize: 20,
+	}
+	if prStr := c.Query("pr_number"); prStr != "" {
+		prNumber, err := strconv.ParseUint(prStr, 10, 32)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "search_commits invalid_pr_number pr_number=%s", prStr)
+			response.BadRequest(c, "Invalid PR number")
+			return
+		}
+		pr := uint32(prNumber)
+		params.PRNumber = &pr
+	}
+	if page := c.Query("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil && p > 0 {
+			params.Page = p
+		}
+	}
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		if ps, err := strconv.Atoi(pageSize); err == nil && ps > 0 {
+			params.PageSize = ps
+		}
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "search_commits author=%s q=%s page=%d page_size=%d",
+		params.Author, params.Query, params.Page, params.PageSize)
+
+	commits, total, err := services.SearchCommits(c, params)
+	if err != nil {
+		logger.LogError(c, logger.ModuleHandler, err, "search_commits failed")
+		response.InternalServerError(c, "Failed to search commits")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "search_commits success total=%d count=%d", total, len(commits))
+	response.Success(c, gin.H{
+		"commits":   commits,
+		"total":     total,
+		"page":      params.Page,
+		"page_size": params.PageSize,
+	})
+}
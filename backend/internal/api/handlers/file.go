@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -98,13 +99,26 @@ func UploadFile(c *gin.Context) {
 	logger.LogInfo(c, logger.ModuleHandler, "upload_file test_run_id=%d", testRunID)
 
 	// 验证测试运行是否存在
-	_, err = services.GetTestRunByID(c, testRunID)
+	testRun, err := services.GetTestRunWithoutCases(c, testRunID)
 	if err != nil {
 		logger.LogWarn(c, logger.ModuleHandler, "upload_file test_run_not_found test_run_id=%d", testRunID)
 		response.NotFound(c, "Test run not found")
 		return
 	}
 
+	// 绑定项目的API Key只能向本项目的测试运行上传文件
+	if err := services.CheckProjectAccess(c, testRun.ProjectID); err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "upload_file project_forbidden test_run_id=%d project_id=%d", testRunID, testRun.ProjectID)
+		response.Forbidden(c, err.Error())
+		return
+	}
+
+	// 可以通过 test_case_id 或 test_case（测例名称）将文件关联到产生它的测例
+	testCaseID, ok := uploadTestCaseID(c, testRunID)
+	if !ok {
+		return
+	}
+
 	// 获取上传的文件
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -133,7 +147,7 @@ func UploadFile(c *gin.Context) {
 	defer src.Close()
 
 	// 保存文件
-	outputFile, err := services.SaveFile(c, testRunID, file.Filename, src)
+	outputFile, err := services.SaveFile(c, testRunID, testCaseID, file.Filename, src)
 	if err != nil {
 		logger.LogError(c, logger.ModuleHandler, err, "upload_file save_file failed test_run_id=%d filename=%s", testRunID, file.Filename)
 		response.InternalServerError(c, "Failed to save file")
@@ -144,6 +158,39 @@ func UploadFile(c *gin.Context) {
 	response.Success(c, outputFile)
 }
 
+// uploadTestCaseID 解析上传文件关联的测例，两个字段都为空时返回 nil
+func uploadTestCaseID(c *gin.Context, testRunID uint64) (*uint64, bool) {
+	caseIDStr := c.PostForm("test_case_id")
+	caseName := c.PostForm("test_case")
+	if caseIDStr == "" && caseName == "" {
+		return nil, true
+	}
+
+	var caseID uint64
+	if caseIDStr != "" {
+		var err error
+		caseID, err = strconv.ParseUint(caseIDStr, 10, 64)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "upload_file invalid_test_case_id id=%s error=%s", caseIDStr, err.Error())
+			response.BadRequest(c, "Invalid test case ID")
+			return nil, false
+		}
+	}
+
+	testCase, err := services.FindTestCase(c, testRunID, caseID, caseName)
+	if err != nil {
+		if errors.Is(err, services.ErrTestCaseNotFound) {
+			logger.LogWarn(c, logger.ModuleHandler, "upload_file test_case_not_found test_run_id=%d error=%s", testRunID, err.Error())
+			response.BadRequest(c, err.Error())
+			return nil, false
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "upload_file find_test_case failed test_run_id=%d", testRunID)
+		response.InternalServerError(c, "Failed to get test case")
+		return nil, false
+	}
+	return &testCase.ID, true
+}
+
 // GetFilesByTestRunID 获取文件列表（公开接口）
 func GetFilesByTestRunID(c *gin.Context) {
 	testRunIDStr := c.Param("id")
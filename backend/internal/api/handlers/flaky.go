@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/dragonos/dragonos-ci-dashboard/internal/services"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/logger"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetFlakyTests 按不稳定度从高到低列出测例（公开接口）
+// 默认只返回 master 分支上被标记为不稳定的测例，flaky_only=false 时返回所有有状态翻转的测例
+func GetFlakyTests(c *gin.Context) {
+	params := services.FlakyTestQueryParams{
+		Branch:    c.DefaultQuery("branch", models.MasterBranch),
+		TestType:  c.Query("test_type"),
+		FlakyOnly: c.DefaultQuery("flaky_only", "true") != "false",
+		Page:      1,
+		PageSize:  20,
+	}
+	if projectIDStr := c.Query("project_id"); projectIDStr != "" {
+		projectID, err := strconv.ParseUint(projectIDStr, 10, 64)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "get_flaky_tests invalid_project_id project_id=%s", projectIDStr)
+			response.BadRequest(c, "Invalid project ID")
+			return
+		}
+		params.ProjectID = &projectID
+	}
+	if page := c.Query("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil && p > 0 {
+			params.Page = p
+		}
+	}
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		if ps, err := strconv.Atoi(pageSize); err == nil && ps > 0 {
+			params.PageSize = ps
+		}
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_flaky_tests branch=%s test_type=%s flaky_only=%t page=%d page_size=%d",
+		params.Branch, params.TestType, params.FlakyOnly, params.Page, params.PageSize)
+
+	entries, total, err := services.QueryFlakyTests(c, params)
+	if err != nil {
+		logger.LogError(c, logger.ModuleHandler, err, "get_flaky_tests failed")
+		response.InternalServerError(c, "Failed to get flaky tests")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_flaky_tests success total=%d count=%d", total, len(entries))
+	response.Success(c, gin.H{
+		"flaky_tests": entries,
+		"total":       total,
+		"page":        params.Page,
+		"page_size":   params.PageSize,
+	})
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/dragonos/dragonos-ci-dashboard/internal/services"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/logger"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetRegressions 列出 master 分支上的测例回归（公开接口）
+// status 默认为 open，可以是 open / closed / all
+func GetRegressions(c *gin.Context) {
+	params := services.RegressionQueryParams{
+		TestType: c.Query("test_type"),
+		Name:     c.Query("name"),
+		Page:     1,
+		PageSize: 20,
+	}
+	status := c.DefaultQuery("status", string(models.RegressionStatusOpen))
+	if status != "all" {
+		params.Status = models.RegressionStatus(status)
+		if !params.Status.IsValid() {
+			logger.LogWarn(c, logger.ModuleHandler, "get_regressions invalid_status status=%s", status)
+			response.BadRequest(c, "Invalid status, must be one of: open, closed, all")
+			return
+		}
+	}
+	if projectIDStr := c.Query("project_id"); projectIDStr != "" {
+		projectID, err := strconv.ParseUint(projectIDStr, 10, 64)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "get_regressions invalid_project_id project_id=%s", projectIDStr)
+			response.BadRequest(c, "Invalid project ID")
+			return
+		}
+		params.ProjectID = &projectID
+	}
+	if page := c.Query("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil && p > 0 {
+			params.Page = p
+		}
+	}
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		if ps, err := strconv.Atoi(pageSize); err == nil && ps > 0 {
+			params.PageSize = ps
+		}
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_regressions status=%s test_type=%s name=%s page=%d page_size=%d",
+		status, params.TestType, params.Name, params.Page, params.PageSize)
+
+	regressions, total, err := services.QueryRegressions(c, params)
+	if err != nil {
+		logger.LogError(c, logger.ModuleHandler, err, "get_regressions failed")
+		response.InternalServerError(c, "Failed to get regressions")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_regressions success total=%d count=%d", total, len(regressions))
+	response.Success(c, gin.H{
+		"regressions": regressions,
+		"total":       total,
+		"page":        params.Page,
+		"page_size":   params.PageSize,
+	})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/services"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/logger"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetTestCaseHistory 获取单个测例在最近公开测试运行中的结果（公开接口）
+// name 为测例完整名称，可以按 project_id、branch、test_type 过滤，limit 为测试运行数量
+func GetTestCaseHistory(c *gin.Context) {
+	params := services.TestCaseHistoryParams{
+		Name:     c.Query("name"),
+		Branch:   c.Query("branch"),
+		TestType: c.Query("test_type"),
+		Limit:    services.DefaultTestCaseHistoryLimit,
+	}
+	if params.Name == "" {
+		logger.LogWarn(c, logger.ModuleHandler, "get_test_case_history missing_name")
+		response.BadRequest(c, "Test case name is required")
+		return
+	}
+	if projectIDStr := c.Query("project_id"); projectIDStr != "" {
+		projectID, err := strconv.ParseUint(projectIDStr, 10, 64)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_case_history invalid_project_id project_id=%s", projectIDStr)
+			response.BadRequest(c, "Invalid project ID")
+			return
+		}
+		params.ProjectID = &projectID
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
+			params.Limit = min(l, services.MaxTestCaseHistoryLimit)
+		}
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_test_case_history name=%s branch=%s test_type=%s limit=%d",
+		params.Name, params.Branch, params.TestType, params.Limit)
+
+	history, err := services.GetTestCaseHistory(c, params)
+	if err != nil {
+		if errors.Is(err, services.ErrTestTypeNotFound) {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_case_history unknown_test_type test_type=%s", params.TestType)
+			response.BadRequest(c, err.Error())
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "get_test_case_history failed name=%s", params.Name)
+		response.InternalServerError(c, "Failed to get test case history")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_test_case_history success name=%s count=%d", params.Name, len(history.Runs))
+	response.Success(c, history)
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/dragonos/dragonos-ci-dashboard/internal/config"
 	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
 	"github.com/dragonos/dragonos-ci-dashboard/internal/services"
 	"github.com/dragonos/dragonos-ci-dashboard/pkg/logger"
@@ -26,6 +31,9 @@ func GetTestRuns(c *gin.Context) {
 	if commitID := c.Query("commit_id"); commitID != "" {
 		params.CommitID = commitID
 	}
+	if testType := c.Query("test_type"); testType != "" {
+		params.TestType = testType
+	}
 	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
 		if startTime, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
 			params.StartTime = &startTime
@@ -42,6 +50,7 @@ func GetTestRuns(c *gin.Context) {
 	if testCaseName := c.Query("test_case_name"); testCaseName != "" {
 		params.TestCaseName = testCaseName
 	}
+	params.Metadata = metadataQuery(c)
 	if page := c.Query("page"); page != "" {
 		if p, err := strconv.Atoi(page); err == nil && p > 0 {
 			params.Page = p
@@ -54,12 +63,22 @@ func GetTestRuns(c *gin.Context) {
 	}
 
 	// 记录查询参数
-	logger.LogInfo(c, logger.ModuleHandler, "query_test_runs branch=%s commit_id=%s status=%s page=%d page_size=%d",
-		params.Branch, params.CommitID, params.Status, params.Page, params.PageSize)
+	logger.LogInfo(c, logger.ModuleHandler, "query_test_runs branch=%s commit_id=%s test_type=%s status=%s page=%d page_size=%d",
+		params.Branch, params.CommitID, params.TestType, params.Status, params.Page, params.PageSize)
 
 	// 公开接口只返回公开的记录
 	testRuns, total, err := services.QueryTestRuns(c, params, false)
 	if err != nil {
+		if errors.Is(err, services.ErrTestTypeNotFound) {
+			logger.LogWarn(c, logger.ModuleHandler, "query_test_runs unknown_test_type test_type=%s", params.TestType)
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvalidMetadataFilter) {
+			logger.LogWarn(c, logger.ModuleHandler, "query_test_runs invalid_metadata_filter error=%s", err.Error())
+			response.BadRequest(c, err.Error())
+			return
+		}
 		logger.LogError(c, logger.ModuleHandler, err, "query_test_runs failed")
 		response.InternalServerError(c, "Failed to query test runs")
 		return
@@ -108,6 +127,7 @@ func GetTestRunByID(c *gin.Context) {
 }
 
 // GetTestCasesByTestRunID 获取测例列表（公开接口）
+// 可以通过 suite 查询参数只返回某个 suite 的测例
 func GetTestCasesByTestRunID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
@@ -132,7 +152,7 @@ func GetTestCasesByTestRunID(c *gin.Context) {
 		return
 	}
 
-	testCases, err := services.GetTestCasesByTestRunID(c, id)
+	testCases, err := services.GetTestCasesByTestRunID(c, id, c.Query("suite"))
 	if err != nil {
 		logger.LogError(c, logger.ModuleHandler, err, "get_test_cases failed test_run_id=%d", id)
 		response.InternalServerError(c, "Failed to get test cases")
@@ -144,6 +164,307 @@ func GetTestCasesByTestRunID(c *gin.Context) {
 	response.Success(c, testCases)
 }
 
+// GetSuiteRollups 按 suite 汇总测试运行的测例状态（公开接口）
+func GetSuiteRollups(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "get_suite_rollups invalid_test_run_id id=%s error=%s", idStr, err.Error())
+		response.BadRequest(c, "Invalid test run ID")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_suite_rollups test_run_id=%d", id)
+
+	// 检查测试运行是否存在且为公开
+	testRun, err := services.GetTestRunWithoutCases(c, id)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "get_suite_rollups test_run_not_found test_run_id=%d", id)
+		response.NotFound(c, "Test run not found")
+		return
+	}
+	if !testRun.IsPublic {
+		logger.LogWarn(c, logger.ModuleHandler, "get_suite_rollups test_run_not_public test_run_id=%d", id)
+		response.NotFound(c, "Test run not found")
+		return
+	}
+
+	rollups, err := services.GetSuiteRollups(c, id)
+	if err != nil {
+		logger.LogError(c, logger.ModuleHandler, err, "get_suite_rollups failed test_run_id=%d", id)
+		response.InternalServerError(c, "Failed to get suite rollups")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_suite_rollups success test_run_id=%d count=%d", id, len(rollups))
+	response.Success(c, rollups)
+}
+
+// GetBaselineComparison 将 PR 运行与其合并基点在 master 上的基线运行比较（公开接口）
+// 返回新失败、新通过、新增和被移除的测例
+func GetBaselineComparison(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "get_baseline_comparison invalid_test_run_id id=%s error=%s", idStr, err.Error())
+		response.BadRequest(c, "Invalid test run ID")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_baseline_comparison test_run_id=%d", id)
+
+	// 检查测试运行是否存在且为公开
+	testRun, err := services.GetTestRunWithoutCases(c, id)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "get_baseline_comparison test_run_not_found test_run_id=%d", id)
+		response.NotFound(c, "Test run not found")
+		return
+	}
+	if !testRun.IsPublic {
+		logger.LogWarn(c, logger.ModuleHandler, "get_baseline_comparison test_run_not_public test_run_id=%d", id)
+		response.NotFound(c, "Test run not found")
+		return
+	}
+
+	comparison, err := services.CompareWithBaseline(c, testRun)
+	if err != nil {
+		if errors.Is(err, services.ErrNoBaseCommit) {
+			logger.LogWarn(c, logger.ModuleHandler, "get_baseline_comparison no_base_commit test_run_id=%d", id)
+			response.BadRequest(c, "Test run has no base commit")
+			return
+		}
+		if errors.Is(err, services.ErrBaselineNotFound) {
+			logger.LogWarn(c, logger.ModuleHandler, "get_baseline_comparison baseline_not_found test_run_id=%d base_commit_id=%s", id, testRun.BaseCommitID)
+			response.NotFound(c, "No baseline run found on master")
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "get_baseline_comparison failed test_run_id=%d", id)
+		response.InternalServerError(c, "Failed to compare with baseline")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_baseline_comparison success test_run_id=%d baseline_id=%d match=%s newly_failing=%d newly_passing=%d",
+		id, comparison.Baseline.ID, comparison.BaselineMatch, len(comparison.NewlyFailing), len(comparison.NewlyPassing))
+	response.Success(c, comparison)
+}
+
+// GetTestRunDiff 逐个测例比较两次测试运行（公开接口）
+// :id 为基准运行，:otherId 为目标运行；返回各分类的数量和 category 指定分类的分页列表
+func GetTestRunDiff(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "get_test_run_diff invalid_test_run_id id=%s error=%s", idStr, err.Error())
+		response.BadRequest(c, "Invalid test run ID")
+		return
+	}
+	otherIDStr := c.Param("otherId")
+	otherID, err := strconv.ParseUint(otherIDStr, 10, 64)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "get_test_run_diff invalid_test_run_id id=%s error=%s", otherIDStr, err.Error())
+		response.BadRequest(c, "Invalid test run ID")
+		return
+	}
+
+	params := services.TestRunDiffParams{
+		Category:           c.Query("category"),
+		MinDurationDeltaMs: services.DefaultDiffMinDurationDeltaMs,
+		Page:               1,
+		PageSize:           20,
+	}
+	if factorStr := c.Query("duration_factor"); factorStr != "" {
+		factor, err := strconv.ParseFloat(factorStr, 64)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_run_diff invalid_duration_factor duration_factor=%s", factorStr)
+			response.BadRequest(c, "Invalid duration factor")
+			return
+		}
+		params.DurationFactor = factor
+	}
+	if deltaStr := c.Query("min_duration_delta_ms"); deltaStr != "" {
+		delta, err := strconv.ParseUint(deltaStr, 10, 32)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_run_diff invalid_min_duration_delta min_duration_delta_ms=%s", deltaStr)
+			response.BadRequest(c, "Invalid minimum duration delta")
+			return
+		}
+		params.MinDurationDeltaMs = uint32(delta)
+	}
+	if page := c.Query("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil && p > 0 {
+			params.Page = p
+		}
+	}
+	if pageSize := c.Query("page_size"); pageSize != "" {
+		if ps, err := strconv.Atoi(pageSize); err == nil && ps > 0 {
+			params.PageSize = ps
+		}
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_test_run_diff test_run_id=%d other_test_run_id=%d category=%s page=%d page_size=%d",
+		id, otherID, params.Category, params.Page, params.PageSize)
+
+	// 两次测试运行都必须存在且为公开
+	var runs [2]*models.TestRun
+	for i, runID := range []uint64{id, otherID} {
+		testRun, err := services.GetTestRunWithoutCases(c, runID)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_run_diff test_run_not_found test_run_id=%d", runID)
+			response.NotFound(c, "Test run not found")
+			return
+		}
+		if !testRun.IsPublic {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_run_diff test_run_not_public test_run_id=%d", runID)
+			response.NotFound(c, "Test run not found")
+			return
+		}
+		runs[i] = testRun
+	}
+
+	diff, err := services.DiffTestRuns(c, runs[0], runs[1], params)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDiffParams) {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_run_diff invalid_params error=%s", err.Error())
+			response.BadRequest(c, err.Error())
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "get_test_run_diff failed test_run_id=%d other_test_run_id=%d", id, otherID)
+		response.InternalServerError(c, "Failed to diff test runs")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_test_run_diff success test_run_id=%d other_test_run_id=%d total=%d", id, otherID, diff.Total)
+	response.Success(c, diff)
+}
+
+// GetTestRunSlowdowns 列出测试运行中耗时超过同一分支历史基线的测例（公开接口）
+// factor 默认使用项目配置的 slowdown_factor，min_duration_delta_ms 默认为100毫秒
+func GetTestRunSlowdowns(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "get_test_run_slowdowns invalid_test_run_id id=%s error=%s", idStr, err.Error())
+		response.BadRequest(c, "Invalid test run ID")
+		return
+	}
+
+	params := services.SlowdownParams{
+		MinDurationDeltaMs: services.DefaultDiffMinDurationDeltaMs,
+	}
+	if factorStr := c.Query("factor"); factorStr != "" {
+		factor, err := strconv.ParseFloat(factorStr, 64)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_run_slowdowns invalid_factor factor=%s", factorStr)
+			response.BadRequest(c, "Invalid slowdown factor")
+			return
+		}
+		params.Factor = factor
+	}
+	if deltaStr := c.Query("min_duration_delta_ms"); deltaStr != "" {
+		delta, err := strconv.ParseUint(deltaStr, 10, 32)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_run_slowdowns invalid_min_duration_delta min_duration_delta_ms=%s", deltaStr)
+			response.BadRequest(c, "Invalid minimum duration delta")
+			return
+		}
+		params.MinDurationDeltaMs = uint32(delta)
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_test_run_slowdowns test_run_id=%d factor=%g min_duration_delta_ms=%d",
+		id, params.Factor, params.MinDurationDeltaMs)
+
+	testRun, err := services.GetTestRunWithoutCases(c, id)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "get_test_run_slowdowns test_run_not_found test_run_id=%d", id)
+		response.NotFound(c, "Test run not found")
+		return
+	}
+	if !testRun.IsPublic {
+		logger.LogWarn(c, logger.ModuleHandler, "get_test_run_slowdowns test_run_not_public test_run_id=%d", id)
+		response.NotFound(c, "Test run not found")
+		return
+	}
+
+	slowdowns, err := services.GetTestRunSlowdowns(c, testRun, params)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSlowdownParams) {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_run_slowdowns invalid_params error=%s", err.Error())
+			response.BadRequest(c, err.Error())
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "get_test_run_slowdowns failed test_run_id=%d", id)
+		response.InternalServerError(c, "Failed to get slowdowns")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_test_run_slowdowns success test_run_id=%d baseline_runs=%d count=%d",
+		id, slowdowns.BaselineRuns, len(slowdowns.Slowdowns))
+	response.Success(c, slowdowns)
+}
+
+// GetTestCaseLog 获取测例的完整日志（公开接口）
+// 超过项目内联长度的日志在测例列表中只返回预览，完整内容通过该接口以纯文本下载
+func GetTestCaseLog(c *gin.Context) {
+	testRunIDStr := c.Param("id")
+	testRunID, err := strconv.ParseUint(testRunIDStr, 10, 64)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "get_test_case_log invalid_test_run_id id=%s error=%s", testRunIDStr, err.Error())
+		response.BadRequest(c, "Invalid test run ID")
+		return
+	}
+
+	caseIDStr := c.Param("caseId")
+	caseID, err := strconv.ParseUint(caseIDStr, 10, 64)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "get_test_case_log invalid_case_id id=%s error=%s", caseIDStr, err.Error())
+		response.BadRequest(c, "Invalid test case ID")
+		return
+	}
+
+	kind := c.Param("kind")
+	logger.LogInfo(c, logger.ModuleHandler, "get_test_case_log test_run_id=%d case_id=%d kind=%s", testRunID, caseID, kind)
+
+	// 检查测试运行是否存在且为公开
+	testRun, err := services.GetTestRunWithoutCases(c, testRunID)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "get_test_case_log test_run_not_found test_run_id=%d", testRunID)
+		response.NotFound(c, "Test run not found")
+		return
+	}
+	if !testRun.IsPublic {
+		logger.LogWarn(c, logger.ModuleHandler, "get_test_case_log test_run_not_public test_run_id=%d", testRunID)
+		response.NotFound(c, "Test run not found")
+		return
+	}
+
+	testCase, err := services.FindTestCase(c, testRunID, caseID, "")
+	if err != nil {
+		if errors.Is(err, services.ErrTestCaseNotFound) {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_case_log test_case_not_found test_run_id=%d case_id=%d", testRunID, caseID)
+			response.NotFound(c, "Test case not found")
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "get_test_case_log find_test_case failed test_run_id=%d case_id=%d", testRunID, caseID)
+		response.InternalServerError(c, "Failed to get test case")
+		return
+	}
+
+	reader, size, err := services.OpenTestCaseLog(testCase, kind)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidLogKind) {
+			logger.LogWarn(c, logger.ModuleHandler, "get_test_case_log invalid_kind kind=%s", kind)
+			response.BadRequest(c, err.Error())
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "get_test_case_log open_log failed case_id=%d kind=%s", caseID, kind)
+		response.InternalServerError(c, "Failed to open test case log")
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, size, "text/plain; charset=utf-8", reader, map[string]string{})
+}
+
 // GetMasterBranchStats 获取master分支最新测试统计数据（公开接口）
 func GetMasterBranchStats(c *gin.Context) {
 	logger.LogInfo(c, logger.ModuleHandler, "get_master_branch_stats")
@@ -159,20 +480,79 @@ func GetMasterBranchStats(c *gin.Context) {
 	response.Success(c, stats)
 }
 
+// testRunUpload 测试结果上传数据，各种上传格式解析后统一转换为该结构
+type testRunUpload struct {
+	ProjectID  *uint64
+	BranchName string
+	CommitID   string
+	TestType   string
+	Status     string
+	Metadata   map[string]string
+	Commit     *services.CommitInput
+	TestCases  []services.TestCaseInput
+
+	PRNumber     *uint32
+	BaseCommitID string
+}
+
+// testCaseRequest JSON格式上传的测例
+// 有 attempts 时 status 可以省略，最终状态为最后一次尝试的状态
+type testCaseRequest struct {
+	Name       string                   `json:"name" binding:"required"`
+	Status     string                   `json:"status" binding:"required_without=Attempts"`
+	DurationMs uint32                   `json:"duration_ms"`
+	ErrorLog   string                   `json:"error_log"`
+	DebugLog   string                   `json:"debug_log"`
+	Attempts   []testCaseAttemptRequest `json:"attempts" binding:"omitempty,dive"`
+}
+
+// testCaseAttemptRequest JSON格式上传的测例单次尝试
+type testCaseAttemptRequest struct {
+	Status     string `json:"status" binding:"required"`
+	DurationMs uint32 `json:"duration_ms"`
+	ErrorLog   string `json:"error_log"`
+	DebugLog   string `json:"debug_log"`
+}
+
+// toTestCaseInputs 将JSON格式的测例转换为测例上传数据
+func toTestCaseInputs(reqs []testCaseRequest) []services.TestCaseInput {
+	testCases := make([]services.TestCaseInput, 0, len(reqs))
+	for _, tc := range reqs {
+		input := services.TestCaseInput{
+			Name:       tc.Name,
+			Status:     models.TestCaseStatus(tc.Status),
+			DurationMs: tc.DurationMs,
+			ErrorLog:   tc.ErrorLog,
+			DebugLog:   tc.DebugLog,
+		}
+		for _, a := range tc.Attempts {
+			input.Attempts = append(input.Attempts, services.TestCaseAttemptInput{
+				Status:     models.TestCaseStatus(a.Status),
+				DurationMs: a.DurationMs,
+				ErrorLog:   a.ErrorLog,
+				DebugLog:   a.DebugLog,
+			})
+		}
+		testCases = append(testCases, input)
+	}
+	return testCases
+}
+
 // CreateTestRun 创建测试运行（受保护接口）
+// status 为 running 时只创建测试运行，之后可以分批追加测例并显式结束
 func CreateTestRun(c *gin.Context) {
 	var req struct {
-		BranchName string `json:"branch_name" binding:"required"`
-		CommitID   string `json:"commit_id" binding:"required"`
-		TestType   string `json:"test_type" binding:"required"`
-		TestCases  []struct {
-			Name       string `json:"name" binding:"required"`
-			Status     string `json:"status" binding:"required"`
-			DurationMs uint32 `json:"duration_ms"`
-			ErrorLog   string `json:"error_log"`
-			DebugLog   string `json:"debug_log"`
-		} `json:"test_cases"`
-		Status string `json:"status"`
+		ProjectID  *uint64               `json:"project_id"`
+		BranchName string                `json:"branch_name" binding:"required"`
+		CommitID   string                `json:"commit_id" binding:"required"`
+		TestType   string                `json:"test_type" binding:"required"`
+		TestCases  []testCaseRequest     `json:"test_cases"`
+		Status     string                `json:"status"`
+		Metadata   map[string]string     `json:"metadata"`
+		Commit     *services.CommitInput `json:"commit"`
+
+		PRNumber     *uint32 `json:"pr_number"`
+		BaseCommitID string  `json:"base_commit_id"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -181,141 +561,446 @@ func CreateTestRun(c *gin.Context) {
 		return
 	}
 
-	logger.LogInfo(c, logger.ModuleHandler, "create_test_run branch=%s commit_id=%s test_type=%s test_cases_count=%d",
-		req.BranchName, req.CommitID, req.TestType, len(req.TestCases))
+	ingestTestRun(c, testRunUpload{
+		ProjectID:  req.ProjectID,
+		BranchName: req.BranchName,
+		CommitID:   req.CommitID,
+		TestType:   req.TestType,
+		Status:     req.Status,
+		Metadata:   req.Metadata,
+		Commit:     req.Commit,
+		TestCases:  toTestCaseInputs(req.TestCases),
+
+		PRNumber:     req.PRNumber,
+		BaseCommitID: req.BaseCommitID,
+	})
+}
 
-	// 验证 commit_id 最少8位
-	if len(req.CommitID) < 8 {
-		logger.LogWarn(c, logger.ModuleHandler, "create_test_run invalid_commit_id commit_id=%s", req.CommitID)
-		response.BadRequest(c, "commit_id must be at least 8 characters")
+// CreateTestRunFromJUnit 通过JUnit XML报告创建测试运行（受保护接口）
+// 运行信息通过查询参数传递，报告内容可以是请求体或 multipart 的 file 字段
+func CreateTestRunFromJUnit(c *gin.Context) {
+	upload, ok := bindUploadQuery(c, "create_test_run_junit")
+	if !ok {
 		return
 	}
 
-	// 验证 test_type 必须为 gvisor
-	if req.TestType != string(models.TestTypeGvisor) {
-		logger.LogWarn(c, logger.ModuleHandler, "create_test_run invalid_test_type test_type=%s", req.TestType)
-		response.BadRequest(c, fmt.Sprintf("test_type must be '%s'", models.TestTypeGvisor))
+	report, ok := openUploadBody(c, "create_test_run_junit")
+	if !ok {
 		return
 	}
-	testType := req.TestType
+	defer report.Close()
 
-	// 自动截取 commit_short_id（前10位）
-	commitShortID := req.CommitID
-	if len(commitShortID) > 10 {
-		commitShortID = commitShortID[:10]
+	testCases, err := services.ParseJUnitReport(report)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "create_test_run_junit parse_failed error=%s", err.Error())
+		response.BadRequest(c, err.Error())
+		return
 	}
+	upload.TestCases = testCases
 
-	// 使用默认项目ID 1
-	const defaultProjectID = 1
+	ingestTestRun(c, upload)
+}
 
-	// 验证并截断日志长度
-	const maxLogLength = 2048
-	for i := range req.TestCases {
-		if len(req.TestCases[i].ErrorLog) > maxLogLength {
-			logger.LogWarn(c, logger.ModuleHandler, "create_test_run error_log_too_long test_case=%s length=%d",
-				req.TestCases[i].Name, len(req.TestCases[i].ErrorLog))
-			response.BadRequest(c, "error_log exceeds maximum length of 2048 characters")
-			return
+// CreateTestRunFromGtestLog 通过gVisor测试（gtest格式）的原始控制台输出创建测试运行（受保护接口）
+// 运行信息通过查询参数传递，日志内容可以是请求体或 multipart 的 file 字段
+func CreateTestRunFromGtestLog(c *gin.Context) {
+	upload, ok := bindUploadQuery(c, "create_test_run_gtest")
+	if !ok {
+		return
+	}
+
+	consoleLog, ok := openUploadBody(c, "create_test_run_gtest")
+	if !ok {
+		return
+	}
+	defer consoleLog.Close()
+
+	testCases, err := services.ParseGtestLog(consoleLog)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "create_test_run_gtest parse_failed error=%s", err.Error())
+		response.BadRequest(c, err.Error())
+		return
+	}
+	upload.TestCases = testCases
+
+	ingestTestRun(c, upload)
+}
+
+// bindUploadQuery 从查询参数中解析非JSON上传格式的运行信息
+func bindUploadQuery(c *gin.Context, op string) (testRunUpload, bool) {
+	upload := testRunUpload{
+		BranchName: c.Query("branch_name"),
+		CommitID:   c.Query("commit_id"),
+		TestType:   c.DefaultQuery("test_type", string(models.TestTypeGvisor)),
+		Status:     c.Query("status"),
+		Metadata:   metadataQuery(c),
+
+		BaseCommitID: c.Query("base_commit_id"),
+	}
+	if upload.BranchName == "" || upload.CommitID == "" {
+		logger.LogWarn(c, logger.ModuleHandler, "%s missing_run_info branch=%s commit_id=%s",
+			op, upload.BranchName, upload.CommitID)
+		response.BadRequest(c, "branch_name and commit_id query parameters are required")
+		return upload, false
+	}
+	if projectIDStr := c.Query("project_id"); projectIDStr != "" {
+		projectID, err := strconv.ParseUint(projectIDStr, 10, 64)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "%s invalid_project_id project_id=%s", op, projectIDStr)
+			response.BadRequest(c, "Invalid project ID")
+			return upload, false
 		}
-		if len(req.TestCases[i].DebugLog) > maxLogLength {
-			logger.LogWarn(c, logger.ModuleHandler, "create_test_run debug_log_too_long test_case=%s length=%d",
-				req.TestCases[i].Name, len(req.TestCases[i].DebugLog))
-			response.BadRequest(c, "debug_log exceeds maximum length of 2048 characters")
-			return
+		upload.ProjectID = &projectID
+	}
+	if prStr := c.Query("pr_number"); prStr != "" {
+		prNumber, err := strconv.ParseUint(prStr, 10, 32)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "%s invalid_pr_number pr_number=%s", op, prStr)
+			response.BadRequest(c, "Invalid PR number")
+			return upload, false
 		}
+		pr := uint32(prNumber)
+		upload.PRNumber = &pr
+	}
+	commit, err := commitQuery(c)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "%s invalid_commit_info error=%s", op, err.Error())
+		response.BadRequest(c, err.Error())
+		return upload, false
 	}
+	upload.Commit = commit
+	return upload, true
+}
 
-	// 创建测试运行
-	testRun, err := services.CreateTestRun(
-		c,
-		defaultProjectID,
-		req.BranchName,
-		req.CommitID,
-		commitShortID,
-		testType,
-	)
-	if err != nil {
-		logger.LogError(c, logger.ModuleHandler, err, "create_test_run failed branch=%s commit_id=%s",
-			req.BranchName, req.CommitID)
-		response.InternalServerError(c, "Failed to create test run")
-		return
-	}
-
-	logger.LogInfo(c, logger.ModuleHandler, "create_test_run success test_run_id=%d", testRun.ID)
-
-	// 批量创建测例
-	if len(req.TestCases) > 0 {
-		testCases := make([]struct {
-			Name       string
-			Status     models.TestCaseStatus
-			DurationMs uint32
-			ErrorLog   string
-			DebugLog   string
-		}, 0, len(req.TestCases))
-
-		for _, tc := range req.TestCases {
-			testCases = append(testCases, struct {
-				Name       string
-				Status     models.TestCaseStatus
-				DurationMs uint32
-				ErrorLog   string
-				DebugLog   string
-			}{
-				Name:       tc.Name,
-				Status:     models.TestCaseStatus(tc.Status),
-				DurationMs: tc.DurationMs,
-				ErrorLog:   tc.ErrorLog,
-				DebugLog:   tc.DebugLog,
-			})
+// commitQuery 解析 commit_author、commit_title、commit_time、commit_parents（逗号分隔）查询参数
+// 都没有时返回 nil
+func commitQuery(c *gin.Context) (*services.CommitInput, error) {
+	commit := services.CommitInput{
+		Author: c.Query("commit_author"),
+		Title:  c.Query("commit_title"),
+	}
+	if parents := c.Query("commit_parents"); parents != "" {
+		commit.Parents = strings.Split(parents, ",")
+	}
+	if timeStr := c.Query("commit_time"); timeStr != "" {
+		committedAt, err := time.Parse(time.RFC3339, timeStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid commit_time: %s", timeStr)
 		}
+		commit.CommittedAt = &committedAt
+	}
+	if commit.Author == "" && commit.Title == "" && commit.CommittedAt == nil && commit.Parents == nil {
+		return nil, nil
+	}
+	return &commit, nil
+}
 
-		if err := services.BatchCreateTestCases(c, testRun.ID, testCases); err != nil {
-			logger.LogError(c, logger.ModuleHandler, err, "batch_create_test_cases failed test_run_id=%d count=%d",
-				testRun.ID, len(testCases))
-			response.InternalServerError(c, "Failed to create test cases")
-			return
+// metadataQuery 解析 meta.<key>=<value> 形式的查询参数
+func metadataQuery(c *gin.Context) map[string]string {
+	var metadata map[string]string
+	for name, values := range c.Request.URL.Query() {
+		key := strings.TrimPrefix(name, "meta.")
+		if key == name || len(values) == 0 {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
 		}
+		metadata[key] = values[0]
+	}
+	return metadata
+}
 
-		logger.LogInfo(c, logger.ModuleHandler, "batch_create_test_cases success test_run_id=%d count=%d",
-			testRun.ID, len(testCases))
+// openUploadBody 获取上传的报告内容，支持 multipart 的 file 字段或直接使用请求体
+func openUploadBody(c *gin.Context, op string) (io.ReadCloser, bool) {
+	maxSize := config.AppConfig.Storage.MaxFileSize
 
-		// 根据测例状态更新测试运行状态
-		allPassed := true
-		hasFailed := false
-		for _, tc := range req.TestCases {
-			if tc.Status == "failed" {
-				hasFailed = true
-				allPassed = false
-				break
-			} else if tc.Status != "passed" {
-				allPassed = false
-			}
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "%s no_file_uploaded error=%s", op, err.Error())
+			response.BadRequest(c, "No file uploaded")
+			return nil, false
 		}
-
-		var finalStatus models.TestRunStatus
-		if hasFailed {
-			finalStatus = models.TestRunStatusFailed
-		} else if allPassed {
-			finalStatus = models.TestRunStatusPassed
-		} else {
-			finalStatus = models.TestRunStatusPassed // 默认通过
+		if file.Size > maxSize {
+			logger.LogWarn(c, logger.ModuleHandler, "%s file_too_large size=%d max_size=%d", op, file.Size, maxSize)
+			response.BadRequest(c, "File size exceeds limit")
+			return nil, false
 		}
+		src, err := file.Open()
+		if err != nil {
+			logger.LogError(c, logger.ModuleHandler, err, "%s open_file failed filename=%s", op, file.Filename)
+			response.InternalServerError(c, "Failed to open file")
+			return nil, false
+		}
+		return src, true
+	}
 
-		// 如果请求中指定了状态，使用请求的状态
-		if req.Status != "" {
-			finalStatus = models.TestRunStatus(req.Status)
+	if c.Request.ContentLength > maxSize {
+		logger.LogWarn(c, logger.ModuleHandler, "%s body_too_large size=%d max_size=%d", op, c.Request.ContentLength, maxSize)
+		response.BadRequest(c, "Request body exceeds limit")
+		return nil, false
+	}
+	return http.MaxBytesReader(c.Writer, c.Request.Body, maxSize), true
+}
+
+// ingestTestRun 解析项目并创建测试运行及其测例，各上传格式共用
+func ingestTestRun(c *gin.Context, req testRunUpload) {
+	logger.LogInfo(c, logger.ModuleHandler, "create_test_run branch=%s commit_id=%s test_type=%s test_cases_count=%d",
+		req.BranchName, req.CommitID, req.TestType, len(req.TestCases))
+
+	// 项目由API Key绑定的项目决定，未绑定的API Key可以指定项目
+	projectID, err := services.ResolveUploadProject(c, req.ProjectID)
+	if err != nil {
+		if errors.Is(err, services.ErrProjectForbidden) {
+			logger.LogWarn(c, logger.ModuleHandler, "create_test_run project_forbidden error=%s", err.Error())
+			response.Forbidden(c, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrProjectNotFound) {
+			logger.LogWarn(c, logger.ModuleHandler, "create_test_run project_not_found error=%s", err.Error())
+			response.BadRequest(c, err.Error())
+			return
 		}
+		logger.LogError(c, logger.ModuleHandler, err, "create_test_run resolve_project failed")
+		response.InternalServerError(c, "Failed to resolve project")
+		return
+	}
+
+	testRun, replayed, err := services.IngestTestRun(c, services.TestRunInput{
+		ProjectID:      projectID,
+		BranchName:     req.BranchName,
+		CommitID:       req.CommitID,
+		TestType:       req.TestType,
+		Status:         req.Status,
+		Metadata:       req.Metadata,
+		Commit:         req.Commit,
+		TestCases:      req.TestCases,
+		PRNumber:       req.PRNumber,
+		BaseCommitID:   req.BaseCommitID,
+		IdempotencyKey: strings.TrimSpace(c.GetHeader("Idempotency-Key")),
+	})
+	respondIngestedTestRun(c, "create_test_run", testRun, replayed, err)
+}
 
-		testRun.Complete(finalStatus)
-		models.DB.Save(testRun)
+// CreateTestRunFromNDJSON 通过NDJSON流创建测试运行（受保护接口），适用于测例数量非常多的测试套件
+// 第一行为运行信息，之后每行一个测例，最后一行为最终状态；测例边读边分批写入
+func CreateTestRunFromNDJSON(c *gin.Context) {
+	logger.LogInfo(c, logger.ModuleHandler, "create_test_run_ndjson content_length=%d", c.Request.ContentLength)
 
-		logger.LogInfo(c, logger.ModuleHandler, "test_run_completed test_run_id=%d status=%s",
-			testRun.ID, finalStatus)
+	maxSize := config.AppConfig.Storage.MaxFileSize
+	if c.Request.ContentLength > maxSize {
+		logger.LogWarn(c, logger.ModuleHandler, "create_test_run_ndjson body_too_large size=%d max_size=%d", c.Request.ContentLength, maxSize)
+		response.BadRequest(c, "Request body exceeds limit")
+		return
+	}
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+	defer body.Close()
+
+	testRun, replayed, err := services.IngestTestRunStream(c, body, strings.TrimSpace(c.GetHeader("Idempotency-Key")))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		logger.LogWarn(c, logger.ModuleHandler, "create_test_run_ndjson body_too_large max_size=%d", maxSize)
+		response.BadRequest(c, "Request body exceeds limit")
+		return
+	}
+	respondIngestedTestRun(c, "create_test_run_ndjson", testRun, replayed, err)
+}
+
+// respondIngestedTestRun 根据创建结果返回测试运行或错误，各上传格式共用
+func respondIngestedTestRun(c *gin.Context, op string, testRun *models.TestRun, replayed bool, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrProjectForbidden):
+			logger.LogWarn(c, logger.ModuleHandler, "%s project_forbidden error=%s", op, err.Error())
+			response.Forbidden(c, err.Error())
+		case errors.Is(err, services.ErrProjectNotFound),
+			errors.Is(err, services.ErrInvalidTestRunUpload),
+			errors.Is(err, services.ErrTestTypeNotFound),
+			errors.Is(err, services.ErrTestTypeNotAllowed):
+			logger.LogWarn(c, logger.ModuleHandler, "%s invalid_request error=%s", op, err.Error())
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, services.ErrIdempotencyKeyConflict):
+			logger.LogWarn(c, logger.ModuleHandler, "%s idempotency_conflict error=%s", op, err.Error())
+			response.Conflict(c, "Idempotency-Key was already used with a different payload")
+		default:
+			logger.LogError(c, logger.ModuleHandler, err, "%s failed", op)
+			response.InternalServerError(c, "Failed to create test run")
+		}
+		return
+	}
+
+	if replayed {
+		logger.LogInfo(c, logger.ModuleHandler, "%s idempotent_replay test_run_id=%d", op, testRun.ID)
+		c.Header("Idempotent-Replayed", "true")
 	}
 
 	// 重新加载关联数据
-	testRun, _ = services.GetTestRunByID(c, testRun.ID)
+	testRun, err = services.GetTestRunByID(c, testRun.ID)
+	if err != nil {
+		logger.LogError(c, logger.ModuleHandler, err, "%s reload failed", op)
+		response.InternalServerError(c, "Failed to get test run")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "%s completed test_run_id=%d status=%s", op, testRun.ID, testRun.Status)
+	response.Success(c, testRun)
+}
+
+// AppendTestCases 向运行中的测试运行追加一批测例（受保护接口）
+func AppendTestCases(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "append_test_cases invalid_test_run_id id=%s error=%s", idStr, err.Error())
+		response.BadRequest(c, "Invalid test run ID")
+		return
+	}
+
+	var req struct {
+		TestCases []testCaseRequest `json:"test_cases" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "append_test_cases invalid_request error=%s", err.Error())
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	testCases := toTestCaseInputs(req.TestCases)
+
+	logger.LogInfo(c, logger.ModuleHandler, "append_test_cases test_run_id=%d count=%d", id, len(testCases))
 
-	logger.LogInfo(c, logger.ModuleHandler, "create_test_run completed test_run_id=%d", testRun.ID)
+	if err := services.AppendTestCases(c, id, testCases); err != nil {
+		if errors.Is(err, services.ErrTestRunNotFound) {
+			logger.LogWarn(c, logger.ModuleHandler, "append_test_cases test_run_not_found test_run_id=%d", id)
+			response.NotFound(c, "Test run not found")
+			return
+		}
+		if errors.Is(err, services.ErrInvalidTestRunUpload) {
+			logger.LogWarn(c, logger.ModuleHandler, "append_test_cases invalid_request error=%s", err.Error())
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrProjectForbidden) {
+			logger.LogWarn(c, logger.ModuleHandler, "append_test_cases project_forbidden test_run_id=%d", id)
+			response.Forbidden(c, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrTestRunNotRunning) {
+			logger.LogWarn(c, logger.ModuleHandler, "append_test_cases test_run_not_running test_run_id=%d", id)
+			response.Conflict(c, "Test run is not running")
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "append_test_cases failed test_run_id=%d", id)
+		response.InternalServerError(c, "Failed to append test cases")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "append_test_cases success test_run_id=%d count=%d", id, len(testCases))
+	response.Success(c, gin.H{
+		"test_run_id": id,
+		"appended":    len(testCases),
+	})
+}
+
+// CompleteTestRun 以指定状态结束运行中的测试运行（受保护接口）
+// reason 为可选的状态说明，如 error 状态的故障原因
+func CompleteTestRun(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "complete_test_run invalid_test_run_id id=%s error=%s", idStr, err.Error())
+		response.BadRequest(c, "Invalid test run ID")
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "complete_test_run invalid_request error=%s", err.Error())
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	status := models.TestRunStatus(req.Status)
+	if !status.IsValid() {
+		logger.LogWarn(c, logger.ModuleHandler, "complete_test_run invalid_status status=%s", req.Status)
+		response.BadRequest(c, fmt.Sprintf("invalid status '%s'", req.Status))
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "complete_test_run test_run_id=%d status=%s", id, status)
+
+	if err := services.CompleteTestRun(c, id, status, req.Reason); err != nil {
+		if errors.Is(err, services.ErrTestRunNotFound) {
+			logger.LogWarn(c, logger.ModuleHandler, "complete_test_run test_run_not_found test_run_id=%d", id)
+			response.NotFound(c, "Test run not found")
+			return
+		}
+		if errors.Is(err, services.ErrProjectForbidden) {
+			logger.LogWarn(c, logger.ModuleHandler, "complete_test_run project_forbidden test_run_id=%d", id)
+			response.Forbidden(c, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvalidStatusTransition) {
+			logger.LogWarn(c, logger.ModuleHandler, "complete_test_run invalid_transition test_run_id=%d error=%s", id, err.Error())
+			response.Conflict(c, err.Error())
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "complete_test_run failed test_run_id=%d", id)
+		response.InternalServerError(c, "Failed to complete test run")
+		return
+	}
+
+	testRun, err := services.GetTestRunByID(c, id)
+	if err != nil {
+		logger.LogError(c, logger.ModuleHandler, err, "complete_test_run reload failed test_run_id=%d", id)
+		response.InternalServerError(c, "Failed to get test run")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "complete_test_run success test_run_id=%d status=%s", id, testRun.Status)
 	response.Success(c, testRun)
 }
+
+// HeartbeatTestRun 记录运行中测试的心跳（受保护接口）
+// 运行中的测试超过项目超时时间没有活动（创建、追加测例、心跳）会被自动取消，长时间运行的测试应定期发送心跳
+func HeartbeatTestRun(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "heartbeat_test_run invalid_test_run_id id=%s error=%s", idStr, err.Error())
+		response.BadRequest(c, "Invalid test run ID")
+		return
+	}
+
+	testRun, err := services.HeartbeatTestRun(c, id)
+	if err != nil {
+		if errors.Is(err, services.ErrTestRunNotFound) {
+			logger.LogWarn(c, logger.ModuleHandler, "heartbeat_test_run test_run_not_found test_run_id=%d", id)
+			response.NotFound(c, "Test run not found")
+			return
+		}
+		if errors.Is(err, services.ErrProjectForbidden) {
+			logger.LogWarn(c, logger.ModuleHandler, "heartbeat_test_run project_forbidden test_run_id=%d", id)
+			response.Forbidden(c, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrTestRunNotRunning) {
+			logger.LogWarn(c, logger.ModuleHandler, "heartbeat_test_run test_run_not_running test_run_id=%d", id)
+			response.Conflict(c, "Test run is not running")
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "heartbeat_test_run failed test_run_id=%d", id)
+		response.InternalServerError(c, "Failed to record heartbeat")
+		return
+	}
+
+	logger.LogDebug(c, logger.ModuleHandler, "heartbeat_test_run success test_run_id=%d", id)
+	response.Success(c, gin.H{
+		"test_run_id":      testRun.ID,
+		"last_activity_at": testRun.LastActivityAt,
+	})
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"github.com/dragonos/dragonos-ci-dashboard/internal/services"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/logger"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetTestTypes 获取可用的测试类型列表（公开接口）
+// 只返回允许使用的项目ID，不返回项目详情
+func GetTestTypes(c *gin.Context) {
+	testTypes, err := services.ListPublicTestTypes(c)
+	if err != nil {
+		logger.LogError(c, logger.ModuleHandler, err, "get_test_types failed")
+		response.InternalServerError(c, "Failed to get test types")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "get_test_types success count=%d", len(testTypes))
+	response.Success(c, testTypes)
+}
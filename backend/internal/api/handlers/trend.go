@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/dragonos/dragonos-ci-dashboard/internal/services"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/logger"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetMasterPassRateTrend 获取 master 分支的通过率趋势（公开接口）
+// 只统计公开的测试运行，可以按 project_id、test_type 过滤（可重复），bucket 为 hour / day / week
+func GetMasterPassRateTrend(c *gin.Context) {
+	params, ok := passRateTrendParams(c, "get_master_pass_rate_trend")
+	if !ok {
+		return
+	}
+	params.Branches = []string{models.MasterBranch}
+	respondPassRateTrend(c, "get_master_pass_rate_trend", params)
+}
+
+// GetPassRateTrend 获取任意分支的通过率趋势（管理接口）
+// 包含私有测试运行，branch 可重复，不传时包含所有分支
+func GetPassRateTrend(c *gin.Context) {
+	params, ok := passRateTrendParams(c, "get_pass_rate_trend")
+	if !ok {
+		return
+	}
+	params.Branches = c.QueryArray("branch")
+	params.IncludePrivate = true
+	respondPassRateTrend(c, "get_pass_rate_trend", params)
+}
+
+// passRateTrendParams 解析通过率趋势的公共查询参数，参数不合法时返回 400 并返回 false
+func passRateTrendParams(c *gin.Context, op string) (services.PassRateTrendParams, bool) {
+	params := services.PassRateTrendParams{
+		TestTypes: c.QueryArray("test_type"),
+		Bucket:    c.DefaultQuery("bucket", services.TrendBucketDay),
+	}
+	for _, projectIDStr := range c.QueryArray("project_id") {
+		projectID, err := strconv.ParseUint(projectIDStr, 10, 64)
+		if err != nil {
+			logger.LogWarn(c, logger.ModuleHandler, "%s invalid_project_id project_id=%s", op, projectIDStr)
+			response.BadRequest(c, "Invalid project ID")
+			return params, false
+		}
+		params.ProjectIDs = append(params.ProjectIDs, projectID)
+	}
+	var err error
+	if params.StartTime, err = rfc3339Query(c, "start_time"); err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "%s invalid_start_time start_time=%s", op, c.Query("start_time"))
+		response.BadRequest(c, "Invalid start_time, must be RFC3339")
+		return params, false
+	}
+	if params.EndTime, err = rfc3339Query(c, "end_time"); err != nil {
+		logger.LogWarn(c, logger.ModuleHandler, "%s invalid_end_time end_time=%s", op, c.Query("end_time"))
+		response.BadRequest(c, "Invalid end_time, must be RFC3339")
+		return params, false
+	}
+	return params, true
+}
+
+// rfc3339Query 解析 RFC3339 格式的时间查询参数，参数为空时返回 nil
+func rfc3339Query(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// respondPassRateTrend 查询通过率趋势并返回
+func respondPassRateTrend(c *gin.Context, op string, params services.PassRateTrendParams) {
+	logger.LogInfo(c, logger.ModuleHandler, "%s bucket=%s branches=%v test_types=%v project_ids=%v",
+		op, params.Bucket, params.Branches, params.TestTypes, params.ProjectIDs)
+
+	trend, err := services.GetPassRateTrend(c, params)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTrendParams) {
+			logger.LogWarn(c, logger.ModuleHandler, "%s invalid_params error=%s", op, err.Error())
+			response.BadRequest(c, err.Error())
+			return
+		}
+		logger.LogError(c, logger.ModuleHandler, err, "%s failed", op)
+		response.InternalServerError(c, "Failed to get pass rate trend")
+		return
+	}
+
+	logger.LogInfo(c, logger.ModuleHandler, "%s success series=%d", op, len(trend.Series))
+	response.Success(c, trend)
+}
@@ -48,9 +48,21 @@ func SetupRouter() *gin.Engine {
 		public.GET("/test-runs", handlers.GetTestRuns)
 		public.GET("/test-runs/:id", handlers.GetTestRunByID)
 		public.GET("/test-runs/:id/test-cases", handlers.GetTestCasesByTestRunID)
+		public.GET("/test-runs/:id/test-cases/:caseId/logs/:kind", handlers.GetTestCaseLog)
+		public.GET("/test-runs/:id/suites", handlers.GetSuiteRollups)
+		public.GET("/test-runs/:id/baseline", handlers.GetBaselineComparison)
+		public.GET("/test-runs/:id/diff/:otherId", handlers.GetTestRunDiff)
+		public.GET("/test-runs/:id/slowdowns", handlers.GetTestRunSlowdowns)
 		public.GET("/test-runs/:id/files", handlers.GetFilesByTestRunID)
 		public.GET("/test-runs/:id/output-files/:fileId", handlers.GetFileByID)
+		public.GET("/test-cases/history", handlers.GetTestCaseHistory)
+		public.GET("/flaky-tests", handlers.GetFlakyTests)
+		public.GET("/regressions", handlers.GetRegressions)
+		public.GET("/commits", handlers.SearchCommits)
+		public.GET("/commits/:sha", handlers.GetCommitBySHA)
 		public.GET("/stats/master", handlers.GetMasterBranchStats)
+		public.GET("/stats/master/trend", handlers.GetMasterPassRateTrend)
+		public.GET("/test-types", handlers.GetTestTypes)
 	}
 
 	// 受保护接口（需要API Key）
@@ -58,6 +70,12 @@ func SetupRouter() *gin.Engine {
 	protected.Use(middleware.APIKeyAuth())
 	{
 		protected.POST("/test-runs", handlers.CreateTestRun)
+		protected.POST("/test-runs/junit", handlers.CreateTestRunFromJUnit)
+		protected.POST("/test-runs/gtest-log", handlers.CreateTestRunFromGtestLog)
+		protected.POST("/test-runs/ndjson", handlers.CreateTestRunFromNDJSON)
+		protected.POST("/test-runs/:id/test-cases", handlers.AppendTestCases)
+		protected.POST("/test-runs/:id/complete", handlers.CompleteTestRun)
+		protected.POST("/test-runs/:id/heartbeat", handlers.HeartbeatTestRun)
 		protected.POST("/test-runs/:id/output-files", handlers.UploadFile)
 	}
 
@@ -80,10 +98,16 @@ func SetupRouter() *gin.Engine {
 		// 仪表板接口
 		admin.GET("/dashboard/stats", handlers.GetDashboardStats)
 		admin.GET("/dashboard/trend", handlers.GetDashboardTrend)
+		admin.GET("/dashboard/pass-rate-trend", handlers.GetPassRateTrend)
 		// 测试运行管理接口
 		admin.GET("/test-runs", handlers.GetTestRunsAdmin)
 		admin.DELETE("/test-runs/:id", handlers.DeleteTestRun)
 		admin.PUT("/test-runs/:id/visibility", handlers.UpdateTestRunVisibility)
+		// 测试类型管理接口
+		admin.GET("/test-types", handlers.GetTestTypesAdmin)
+		admin.POST("/test-types", handlers.CreateTestType)
+		admin.PUT("/test-types/:id", handlers.UpdateTestType)
+		admin.DELETE("/test-types/:id", handlers.DeleteTestType)
 		// 系统配置接口
 		admin.GET("/system-configs", handlers.GetSystemConfigs)
 		admin.GET("/system-configs/:key", handlers.GetSystemConfig)
@@ -10,13 +10,15 @@ import (
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Server   ServerConfig
-	Storage  StorageConfig
-	JWT      JWTConfig
-	APIKey   APIKeyConfig
-	Log      LogConfig
-	CORS     CORSConfig
+	Database  DatabaseConfig
+	Server    ServerConfig
+	Storage   StorageConfig
+	JWT       JWTConfig
+	APIKey    APIKeyConfig
+	Log       LogConfig
+	CORS      CORSConfig
+	Reaper    ReaperConfig
+	Analytics AnalyticsConfig
 }
 
 type DatabaseConfig struct {
@@ -58,6 +60,14 @@ type CORSConfig struct {
 	AllowOrigins []string
 }
 
+type ReaperConfig struct {
+	IntervalSeconds int // 检查无活动的运行中测试的间隔（秒），为0时不启动
+}
+
+type AnalyticsConfig struct {
+	IntervalSeconds int // 处理登记的分析数据更新（不稳定测例等）的间隔（秒），为0时不启动
+}
+
 var AppConfig *Config
 
 func Load() error {
@@ -105,6 +115,10 @@ func Load() error {
 
 	viper.SetDefault("cors.allow_origins", []string{"http://localhost:3000", "http://localhost:5173"})
 
+	viper.SetDefault("reaper.interval_seconds", 60)
+
+	viper.SetDefault("analytics.interval_seconds", 10)
+
 	// 从环境变量读取配置（环境变量优先级最高）
 	viper.AutomaticEnv()
 
@@ -146,6 +160,12 @@ func Load() error {
 		CORS: CORSConfig{
 			AllowOrigins: getConfigStringSlice("CORS_ALLOW_ORIGINS", "cors.allow_origins", []string{"http://localhost:3000", "http://localhost:5173"}),
 		},
+		Reaper: ReaperConfig{
+			IntervalSeconds: getConfigInt("REAPER_INTERVAL_SECONDS", "reaper.interval_seconds", 60),
+		},
+		Analytics: AnalyticsConfig{
+			IntervalSeconds: getConfigInt("ANALYTICS_INTERVAL_SECONDS", "analytics.interval_seconds", 10),
+		},
 	}
 
 	// 确保存储目录存在
@@ -198,6 +218,12 @@ func bindEnvVars() {
 
 	// CORS配置
 	viper.BindEnv("CORS_ALLOW_ORIGINS", "CORS_ALLOW_ORIGINS")
+
+	// 超时运行清理配置
+	viper.BindEnv("REAPER_INTERVAL_SECONDS", "REAPER_INTERVAL_SECONDS")
+
+	// 分析任务配置
+	viper.BindEnv("ANALYTICS_INTERVAL_SECONDS", "ANALYTICS_INTERVAL_SECONDS")
 }
 
 // getConfigValue 获取配置值，优先级：环境变量 > 配置文件 > 默认值
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// AnalyticsRefresh 等待重新计算运行历史分析数据（不稳定测例、回归）的范围
+// 测试运行结束、可见性变化或删除时在同一事务中登记，由后台任务在事务提交后处理；
+// 同一范围只保留一条记录，处理期间再次登记时 Version 增加，处理完成后保留该记录
+type AnalyticsRefresh struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID   uint64    `gorm:"type:bigint unsigned;not null;uniqueIndex:idx_analytics_scope,priority:1" json:"project_id"`
+	BranchName  string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_analytics_scope,priority:2" json:"branch_name"`
+	TestType    string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_analytics_scope,priority:3" json:"test_type"`
+	Version     uint64    `gorm:"type:bigint unsigned;not null;default:1" json:"version"`
+	RequestedAt time.Time `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP" json:"requested_at"`
+}
+
+// TableName 指定表名
+func (AnalyticsRefresh) TableName() string {
+	return "analytics_refreshes"
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Commit 提交信息，同一个提交的所有测试运行共享一条记录
+// 上传测试结果时创建，上传数据中带有提交信息时更新
+type Commit struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	SHA         string     `gorm:"column:sha;type:varchar(40);not null;uniqueIndex" json:"sha"`
+	ShortSHA    string     `gorm:"column:short_sha;type:varchar(10);not null;index" json:"short_sha"`
+	Author      string     `gorm:"type:varchar(255);not null;default:'';index" json:"author,omitempty"`
+	Title       string     `gorm:"type:varchar(500);not null;default:''" json:"title,omitempty"` // 提交信息的第一行
+	CommittedAt *time.Time `gorm:"type:datetime;index" json:"committed_at,omitempty"`
+	ParentSHAs  string     `gorm:"column:parent_shas;type:varchar(1000);not null;default:''" json:"-"` // 逗号分隔
+	PRNumber    *uint32    `gorm:"column:pr_number;type:int unsigned;index" json:"pr_number,omitempty"`
+	CreatedAt   time.Time  `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	Parents []string `gorm:"-" json:"parents,omitempty"`
+
+	// 关联关系，test_runs.commit_id 可能早于提交记录存在，不创建外键
+	TestRuns []TestRun `gorm:"foreignKey:CommitID;references:SHA;-:migration" json:"test_runs,omitempty"`
+}
+
+// TableName 指定表名
+func (Commit) TableName() string {
+	return "commits"
+}
+
+// BeforeCreate 创建前钩子
+func (cm *Commit) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now()
+	cm.CreatedAt = now
+	cm.UpdatedAt = now
+	return nil
+}
+
+// AfterFind 查询后钩子，拆分父提交列表
+func (cm *Commit) AfterFind(tx *gorm.DB) error {
+	if cm.ParentSHAs != "" {
+		cm.Parents = strings.Split(cm.ParentSHAs, ",")
+	}
+	return nil
+}
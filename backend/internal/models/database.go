@@ -52,6 +52,14 @@ func InitDatabase() error {
 		&APIKey{},
 		&User{},
 		&SystemConfig{},
+		&TestTypeDefinition{},
+		&TestRunMetadata{},
+		&TestCaseAttempt{},
+		&Commit{},
+		&FlakyTestCase{},
+		&Regression{},
+		&RegressionCursor{},
+		&AnalyticsRefresh{},
 	); err != nil {
 		return fmt.Errorf("failed to auto migrate: %w", err)
 	}
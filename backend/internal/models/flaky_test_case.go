@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// FlakyTestCase 测例在最近测试运行中的不稳定度
+// 按项目、分支和测试类型分别统计，测试运行结束后由后台任务重新计算；只保存有状态翻转的测例
+type FlakyTestCase struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement" json:"-"`
+	ProjectID  uint64 `gorm:"type:bigint unsigned;not null;index:idx_flaky_scope,priority:1" json:"project_id"`
+	BranchName string `gorm:"type:varchar(255);not null;index:idx_flaky_scope,priority:2" json:"branch_name"`
+	TestType   string `gorm:"type:varchar(50);not null;index:idx_flaky_scope,priority:3" json:"test_type"`
+	Name       string `gorm:"type:varchar(500);not null;index" json:"name"`
+
+	Score           float64   `gorm:"type:double;not null;default:0;index" json:"score"` // 0~1，越大越不稳定
+	IsFlaky         bool      `gorm:"type:boolean;not null;default:false" json:"is_flaky"`
+	Runs            uint32    `gorm:"type:int unsigned;not null;default:0" json:"runs"`              // 窗口内有结果（未跳过）的运行次数
+	Flips           uint32    `gorm:"type:int unsigned;not null;default:0" json:"flips"`             // 相邻两次结果在通过和失败之间切换的次数
+	SameCommitFlips uint32    `gorm:"type:int unsigned;not null;default:0" json:"same_commit_flips"` // 同一提交上既通过又失败的次数（包括重试后通过）
+	LastFailedRunID *uint64   `gorm:"type:bigint unsigned" json:"last_failed_run_id,omitempty"`
+	UpdatedAt       time.Time `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (FlakyTestCase) TableName() string {
+	return "flaky_test_cases"
+}
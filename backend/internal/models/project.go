@@ -14,11 +14,30 @@ type Project struct {
 	CreatedAt   time.Time `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt   time.Time `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
 
+	// 测例日志超过该长度（字节）时只在数据库中保存截断的预览，完整内容保存为文件
+	LogInlineLimit uint32 `gorm:"type:int unsigned;not null;default:2048" json:"log_inline_limit"`
+	// 自动推断状态时，全部测例都被跳过的测试运行记为该状态（passed 或 failed）
+	SkippedOnlyStatus TestRunStatus `gorm:"type:varchar(20);not null;default:'passed'" json:"skipped_only_status"`
+	// 运行中的测试超过该时间（分钟）没有活动时自动取消
+	RunTimeoutMinutes uint32 `gorm:"type:int unsigned;not null;default:120" json:"run_timeout_minutes"`
+	// 计算测例不稳定度时使用的最近测试运行数量（按分支和测试类型分别统计）
+	FlakyWindowRuns uint32 `gorm:"type:int unsigned;not null;default:30" json:"flaky_window_runs"`
+	// 测例耗时达到同一分支历史耗时中位数的该倍数时视为变慢
+	SlowdownFactor float64 `gorm:"type:double;not null;default:2" json:"slowdown_factor"`
+
 	// 关联关系
 	TestRuns []TestRun `gorm:"foreignKey:ProjectID" json:"test_runs,omitempty"`
 	APIKeys  []APIKey  `gorm:"foreignKey:ProjectID" json:"api_keys,omitempty"`
 }
 
+// 项目配置的默认值
+const (
+	DefaultLogInlineLimit    = 2048 // 测例日志内联长度（字节）
+	DefaultRunTimeoutMinutes = 120  // 运行中的测试无活动超时时间（分钟）
+	DefaultFlakyWindowRuns   = 30   // 计算测例不稳定度时使用的最近测试运行数量
+	DefaultSlowdownFactor    = 2.0  // 测例变慢的耗时倍数
+)
+
 // TableName 指定表名
 func (Project) TableName() string {
 	return "projects"
@@ -27,6 +46,21 @@ func (Project) TableName() string {
 // BeforeCreate 创建前钩子
 func (p *Project) BeforeCreate(tx *gorm.DB) error {
 	now := time.Now()
+	if p.LogInlineLimit == 0 {
+		p.LogInlineLimit = DefaultLogInlineLimit
+	}
+	if p.SkippedOnlyStatus == "" {
+		p.SkippedOnlyStatus = TestRunStatusPassed
+	}
+	if p.RunTimeoutMinutes == 0 {
+		p.RunTimeoutMinutes = DefaultRunTimeoutMinutes
+	}
+	if p.FlakyWindowRuns == 0 {
+		p.FlakyWindowRuns = DefaultFlakyWindowRuns
+	}
+	if p.SlowdownFactor == 0 {
+		p.SlowdownFactor = DefaultSlowdownFactor
+	}
 	p.CreatedAt = now
 	p.UpdatedAt = now
 	return nil
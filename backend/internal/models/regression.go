@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+)
+
+// RegressionStatus 回归状态
+type RegressionStatus string
+
+const (
+	RegressionStatusOpen   RegressionStatus = "open"   // 测例仍在失败
+	RegressionStatusClosed RegressionStatus = "closed" // 测例已经重新通过
+)
+
+// IsValid 检查状态值是否合法
+func (s RegressionStatus) IsValid() bool {
+	switch s {
+	case RegressionStatusOpen, RegressionStatusClosed:
+		return true
+	}
+	return false
+}
+
+// Regression master 分支上的测例回归记录
+// 测例在 master 上通过后开始失败时创建，记录最后一次通过和第一次失败的测试运行及其之间的提交范围；
+// 测例重新通过时自动关闭。同一项目、测试类型和测例同时最多有一条未关闭的回归
+type Regression struct {
+	ID        uint64           `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID uint64           `gorm:"type:bigint unsigned;not null;index:idx_regression_scope,priority:1" json:"project_id"`
+	TestType  string           `gorm:"type:varchar(50);not null;index:idx_regression_scope,priority:2" json:"test_type"`
+	Name      string           `gorm:"type:varchar(500);not null;index" json:"name"`
+	Status    RegressionStatus `gorm:"type:enum('open','closed');not null;default:'open';index:idx_regression_scope,priority:3" json:"status"`
+
+	// 提交范围：最后一次通过的提交（不含）到第一次失败的提交（含）之间引入了回归
+	LastGoodRunID    *uint64 `gorm:"type:bigint unsigned" json:"last_good_run_id"`
+	LastGoodCommitID string  `gorm:"type:varchar(40);not null" json:"last_good_commit_id"`
+	FirstBadRunID    *uint64 `gorm:"type:bigint unsigned" json:"first_bad_run_id"`
+	FirstBadCommitID string  `gorm:"type:varchar(40);not null" json:"first_bad_commit_id"`
+
+	// 测例重新通过的测试运行，回归关闭时设置
+	FixedRunID    *uint64 `gorm:"type:bigint unsigned" json:"fixed_run_id,omitempty"`
+	FixedCommitID string  `gorm:"type:varchar(40);not null;default:''" json:"fixed_commit_id,omitempty"`
+
+	// 引用的测试运行被设为私有或删除后需要重新检查
+	Recheck bool `gorm:"not null;default:false" json:"-"`
+
+	OpenedAt  time.Time  `gorm:"type:datetime;not null;index" json:"opened_at"`
+	ClosedAt  *time.Time `gorm:"type:datetime" json:"closed_at,omitempty"`
+	UpdatedAt time.Time  `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Regression) TableName() string {
+	return "regressions"
+}
+
+// RegressionCursor 项目和测试类型在 master 上回归检测的进度
+// 分析任务按创建时间顺序检测该运行之后的每一次公开的 master 运行
+type RegressionCursor struct {
+	ProjectID        uint64    `gorm:"primaryKey;type:bigint unsigned;autoIncrement:false" json:"project_id"`
+	TestType         string    `gorm:"primaryKey;type:varchar(50)" json:"test_type"`
+	LastRunID        uint64    `gorm:"type:bigint unsigned;not null" json:"last_run_id"`
+	LastRunCreatedAt time.Time `gorm:"type:datetime;not null" json:"last_run_created_at"`
+	UpdatedAt        time.Time `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (RegressionCursor) TableName() string {
+	return "regression_cursors"
+}
@@ -15,10 +15,19 @@ const (
 	TestCaseStatusSkipped TestCaseStatus = "skipped"
 )
 
+// IsValid 检查状态值是否合法
+func (s TestCaseStatus) IsValid() bool {
+	switch s {
+	case TestCaseStatusPassed, TestCaseStatusFailed, TestCaseStatusSkipped:
+		return true
+	}
+	return false
+}
+
 // TestCase 测例详情模型
 type TestCase struct {
 	ID         uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
-	TestRunID  uint64         `gorm:"type:bigint unsigned;not null;index" json:"test_run_id"`
+	TestRunID  uint64         `gorm:"type:bigint unsigned;not null;index;index:idx_test_run_suite,priority:1" json:"test_run_id"`
 	Name       string         `gorm:"type:varchar(500);not null;index" json:"name"`
 	Status     TestCaseStatus `gorm:"type:enum('passed','failed','skipped');not null;index" json:"status"`
 	DurationMs uint32         `gorm:"type:int unsigned;default:0" json:"duration_ms"`
@@ -26,8 +35,30 @@ type TestCase struct {
 	DebugLog   string         `gorm:"type:text" json:"debug_log,omitempty"`
 	CreatedAt  time.Time      `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 
+	// 日志超过项目的内联长度时，ErrorLog/DebugLog 只保存预览，完整内容保存在存储目录中
+	ErrorLogTruncated bool   `gorm:"type:boolean;not null;default:false" json:"error_log_truncated,omitempty"`
+	DebugLogTruncated bool   `gorm:"type:boolean;not null;default:false" json:"debug_log_truncated,omitempty"`
+	ErrorLogPath      string `gorm:"type:varchar(1000);not null;default:''" json:"-"`
+	DebugLogPath      string `gorm:"type:varchar(1000);not null;default:''" json:"-"`
+
+	// 由名称解析出的层级：[实例化前缀/]Suite.Test[/参数]
+	// 如 SocketInetLoopbackTest/SocketInetReusePortTest.TcpPortReuseMultiThread/0
+	Suite    string `gorm:"type:varchar(500);not null;default:'';index:idx_test_run_suite,priority:2" json:"suite"`
+	TestName string `gorm:"type:varchar(500);not null;default:''" json:"test_name"`
+	Param    string `gorm:"type:varchar(500);not null;default:''" json:"param,omitempty"`
+
+	// 重试：Status 为最后一次尝试的结果，PassedOnRetry 表示之前的尝试失败、重试后通过
+	AttemptCount  uint16 `gorm:"type:smallint unsigned;not null;default:1" json:"attempt_count"`
+	PassedOnRetry bool   `gorm:"type:boolean;not null;default:false;index" json:"passed_on_retry"`
+
+	// 不稳定度，根据同一项目、分支和测试类型的最近测试运行计算，只在测例列表中返回
+	IsFlaky    bool    `gorm:"-" json:"is_flaky"`
+	FlakyScore float64 `gorm:"-" json:"flaky_score,omitempty"`
+
 	// 关联关系
-	TestRun TestRun `gorm:"foreignKey:TestRunID" json:"test_run,omitempty"`
+	TestRun     TestRun           `gorm:"foreignKey:TestRunID" json:"test_run,omitempty"`
+	Attempts    []TestCaseAttempt `gorm:"foreignKey:TestCaseID" json:"attempts,omitempty"`
+	OutputFiles []TestOutputFile  `gorm:"foreignKey:TestCaseID" json:"output_files,omitempty"`
 }
 
 // TableName 指定表名
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TestCaseAttempt 测例的单次执行记录
+// 失败后重试的测例每次执行都会保存一条记录，TestCase 中保存最终结果
+type TestCaseAttempt struct {
+	ID         uint64         `gorm:"primaryKey;autoIncrement" json:"-"`
+	TestCaseID uint64         `gorm:"type:bigint unsigned;not null;index" json:"-"`
+	Attempt    uint16         `gorm:"type:smallint unsigned;not null" json:"attempt"` // 从1开始
+	Status     TestCaseStatus `gorm:"type:enum('passed','failed','skipped');not null" json:"status"`
+	DurationMs uint32         `gorm:"type:int unsigned;default:0" json:"duration_ms"`
+	ErrorLog   string         `gorm:"type:text" json:"error_log,omitempty"`
+	DebugLog   string         `gorm:"type:text" json:"debug_log,omitempty"`
+	CreatedAt  time.Time      `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP" json:"-"`
+}
+
+// TableName 指定表名
+func (TestCaseAttempt) TableName() string {
+	return "test_case_attempts"
+}
+
+// BeforeCreate 创建前钩子
+func (a *TestCaseAttempt) BeforeCreate(tx *gorm.DB) error {
+	a.CreatedAt = time.Now()
+	return nil
+}
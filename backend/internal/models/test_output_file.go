@@ -16,6 +16,9 @@ type TestOutputFile struct {
 	MimeType  string    `gorm:"type:varchar(100)" json:"mime_type,omitempty"`
 	CreatedAt time.Time `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 
+	// 产生该文件的测例（如某个测例的 strace 输出、core 文件），为空时属于整个测试运行
+	TestCaseID *uint64 `gorm:"type:bigint unsigned;index" json:"test_case_id,omitempty"`
+
 	// 关联关系
 	TestRun TestRun `gorm:"foreignKey:TestRunID" json:"test_run,omitempty"`
 }
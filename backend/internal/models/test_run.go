@@ -14,8 +14,27 @@ const (
 	TestRunStatusPassed    TestRunStatus = "passed"
 	TestRunStatusFailed    TestRunStatus = "failed"
 	TestRunStatusCancelled TestRunStatus = "cancelled"
+	TestRunStatusError     TestRunStatus = "error" // 基础设施故障（如runner崩溃、QEMU启动失败），与测例失败区分
 )
 
+// IsValid 检查状态值是否合法
+func (s TestRunStatus) IsValid() bool {
+	switch s {
+	case TestRunStatusRunning, TestRunStatusPassed, TestRunStatusFailed, TestRunStatusCancelled, TestRunStatusError:
+		return true
+	}
+	return false
+}
+
+// testRunTransitions 测试运行状态机中合法的状态转换
+// 只有运行中的测试可以结束，已结束的状态不再改变
+var testRunTransitions = map[TestRunStatus][]TestRunStatus{
+	TestRunStatusRunning: {TestRunStatusPassed, TestRunStatusFailed, TestRunStatusCancelled, TestRunStatusError},
+}
+
+// MasterBranch 主分支名称，统计数据和基线比较都以该分支为准
+const MasterBranch = "master"
+
 // TestType 测试类型
 type TestType string
 
@@ -27,21 +46,83 @@ const (
 // TestRun 测试运行记录模型
 type TestRun struct {
 	ID            uint64        `gorm:"primaryKey;autoIncrement" json:"id"`
-	ProjectID     uint64        `gorm:"type:bigint unsigned;not null;index" json:"project_id"`
+	ProjectID     uint64        `gorm:"type:bigint unsigned;not null;index;uniqueIndex:idx_project_idempotency_key,priority:1" json:"project_id"`
 	BranchName    string        `gorm:"type:varchar(255);not null;index" json:"branch_name"`
 	CommitID      string        `gorm:"type:varchar(40);not null;index" json:"commit_id"`
 	CommitShortID string        `gorm:"type:varchar(10);not null;index" json:"commit_short_id"`
 	TestType      string        `gorm:"type:varchar(50);not null;default:'gvisor';index" json:"test_type"`
-	Status        TestRunStatus `gorm:"type:enum('passed','failed','running','cancelled');not null;default:'running';index" json:"status"`
+	Status        TestRunStatus `gorm:"type:enum('passed','failed','running','cancelled','error');not null;default:'running';index" json:"status"`
 	IsPublic      bool          `gorm:"type:boolean;not null;default:true;index" json:"is_public"`
 	StartedAt     *time.Time    `gorm:"type:datetime" json:"started_at,omitempty"`
 	CompletedAt   *time.Time    `gorm:"type:datetime" json:"completed_at,omitempty"`
 	CreatedAt     time.Time     `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
 
+	// 幂等上传：同一项目下相同的幂等键只会创建一次测试运行
+	IdempotencyKey *string `gorm:"type:varchar(255);uniqueIndex:idx_project_idempotency_key,priority:2" json:"idempotency_key,omitempty"`
+	PayloadHash    string  `gorm:"type:varchar(64);not null;default:''" json:"-"` // 上传内容的哈希，用于识别冲突的重放
+
+	// 最近一次活动（创建、追加测例、心跳）的时间，运行中的测试超过项目超时时间没有活动时会被自动取消
+	LastActivityAt *time.Time `gorm:"type:datetime;index" json:"last_activity_at,omitempty"`
+	StatusReason   string     `gorm:"type:varchar(255);not null;default:''" json:"status_reason,omitempty"` // 状态说明，如自动取消的原因
+
+	// PR 运行的 PR 编号和合并基点（master 上的提交），用于与 master 上的基线运行比较
+	PRNumber     *uint32 `gorm:"column:pr_number;type:int unsigned;index" json:"pr_number,omitempty"`
+	BaseCommitID string  `gorm:"type:varchar(40);not null;default:''" json:"base_commit_id,omitempty"`
+
+	// 测例统计，写入测例和测试运行结束时更新，列表中无需再统计测例
+	TestRunSummary `gorm:"embedded"`
+
 	// 关联关系
-	Project     Project          `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
-	TestCases   []TestCase       `gorm:"foreignKey:TestRunID" json:"test_cases,omitempty"`
-	OutputFiles []TestOutputFile `gorm:"foreignKey:TestRunID" json:"output_files,omitempty"`
+	Project     Project           `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
+	TestCases   []TestCase        `gorm:"foreignKey:TestRunID" json:"test_cases,omitempty"`
+	OutputFiles []TestOutputFile  `gorm:"foreignKey:TestRunID" json:"output_files,omitempty"`
+	Metadata    []TestRunMetadata `gorm:"foreignKey:TestRunID" json:"metadata,omitempty"`
+	Commit      *Commit           `gorm:"foreignKey:CommitID;references:SHA;-:migration" json:"commit,omitempty"`
+}
+
+// TestRunSummary 测试运行的测例统计
+type TestRunSummary struct {
+	TotalCases         uint32 `gorm:"type:int unsigned;not null;default:0" json:"total_cases"`
+	PassedCases        uint32 `gorm:"type:int unsigned;not null;default:0" json:"passed_cases"`
+	FailedCases        uint32 `gorm:"type:int unsigned;not null;default:0" json:"failed_cases"`
+	SkippedCases       uint32 `gorm:"type:int unsigned;not null;default:0" json:"skipped_cases"`
+	PassedOnRetryCases uint32 `gorm:"type:int unsigned;not null;default:0" json:"passed_on_retry_cases"` // 包含在 PassedCases 中
+	TotalDurationMs    uint64 `gorm:"type:bigint unsigned;not null;default:0" json:"total_duration_ms"`
+}
+
+// Add 将测例计入统计
+func (s *TestRunSummary) Add(tc *TestCase) {
+	s.TotalCases++
+	switch tc.Status {
+	case TestCaseStatusPassed:
+		s.PassedCases++
+	case TestCaseStatusFailed:
+		s.FailedCases++
+	case TestCaseStatusSkipped:
+		s.SkippedCases++
+	}
+	if tc.PassedOnRetry {
+		s.PassedOnRetryCases++
+	}
+	s.TotalDurationMs += uint64(tc.DurationMs)
+}
+
+// Merge 合并另一组统计
+func (s *TestRunSummary) Merge(other TestRunSummary) {
+	s.TotalCases += other.TotalCases
+	s.PassedCases += other.PassedCases
+	s.FailedCases += other.FailedCases
+	s.SkippedCases += other.SkippedCases
+	s.PassedOnRetryCases += other.PassedOnRetryCases
+	s.TotalDurationMs += other.TotalDurationMs
+}
+
+// PassRate 通过的测例占全部测例的百分比
+func (s TestRunSummary) PassRate() float64 {
+	if s.TotalCases == 0 {
+		return 0
+	}
+	return float64(s.PassedCases) / float64(s.TotalCases) * 100.0
 }
 
 // TableName 指定表名
@@ -55,18 +136,44 @@ func (tr *TestRun) BeforeCreate(tx *gorm.DB) error {
 	if tr.StartedAt == nil {
 		tr.StartedAt = &now
 	}
-	tr.CreatedAt = now
+	if tr.LastActivityAt == nil {
+		tr.LastActivityAt = &now
+	}
+	// 导入历史数据时保留原始创建时间
+	if tr.CreatedAt.IsZero() {
+		tr.CreatedAt = now
+	}
 	return nil
 }
 
 // IsCompleted 检查测试是否已完成
 func (tr *TestRun) IsCompleted() bool {
-	return tr.Status == TestRunStatusPassed || tr.Status == TestRunStatusFailed || tr.Status == TestRunStatusCancelled
+	return tr.Status.IsValid() && tr.Status != TestRunStatusRunning
+}
+
+// CanTransitionTo 检查是否允许从当前状态转换到目标状态
+func (tr *TestRun) CanTransitionTo(status TestRunStatus) bool {
+	for _, next := range testRunTransitions[tr.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Touch 记录测试运行的活动时间
+func (tr *TestRun) Touch() {
+	now := time.Now()
+	tr.LastActivityAt = &now
 }
 
 // Complete 完成测试运行
 func (tr *TestRun) Complete(status TestRunStatus) {
+	tr.CompleteAt(status, time.Now())
+}
+
+// CompleteAt 以指定的完成时间完成测试运行
+func (tr *TestRun) CompleteAt(status TestRunStatus, completedAt time.Time) {
 	tr.Status = status
-	now := time.Now()
-	tr.CompletedAt = &now
+	tr.CompletedAt = &completedAt
 }
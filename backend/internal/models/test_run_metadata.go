@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TestRunMetadata 测试运行的自定义元数据（如 runner 主机名、CI 工作流地址、QEMU 版本等）
+type TestRunMetadata struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"-"`
+	TestRunID uint64    `gorm:"type:bigint unsigned;not null;uniqueIndex:idx_test_run_meta_key,priority:1" json:"-"`
+	Key       string    `gorm:"column:meta_key;type:varchar(64);not null;uniqueIndex:idx_test_run_meta_key,priority:2;index:idx_meta_key_value,priority:1" json:"key"`
+	Value     string    `gorm:"column:meta_value;type:varchar(255);not null;index:idx_meta_key_value,priority:2" json:"value"`
+	CreatedAt time.Time `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP" json:"-"`
+}
+
+// TableName 指定表名
+func (TestRunMetadata) TableName() string {
+	return "test_run_metadata"
+}
+
+// BeforeCreate 创建前钩子
+func (m *TestRunMetadata) BeforeCreate(tx *gorm.DB) error {
+	m.CreatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TestTypeDefinition 测试类型注册表
+// TestRun.TestType 存储的是这里的 Name
+type TestTypeDefinition struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string    `gorm:"type:varchar(50);not null;uniqueIndex" json:"name"`
+	DisplayName string    `gorm:"type:varchar(255);not null" json:"display_name"`
+	Description string    `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// 允许使用该测试类型的项目，为空表示所有项目都可以使用
+	AllowedProjects []Project `gorm:"many2many:test_type_projects;joinForeignKey:TestTypeID;joinReferences:ProjectID" json:"allowed_projects"`
+}
+
+// TableName 指定表名
+func (TestTypeDefinition) TableName() string {
+	return "test_types"
+}
+
+// BeforeCreate 创建前钩子
+func (tt *TestTypeDefinition) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now()
+	tt.CreatedAt = now
+	tt.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate 更新前钩子
+func (tt *TestTypeDefinition) BeforeUpdate(tx *gorm.DB) error {
+	tt.UpdatedAt = time.Now()
+	return nil
+}
+
+// AllowsProject 检查项目是否允许使用该测试类型
+func (tt *TestTypeDefinition) AllowsProject(projectID uint64) bool {
+	if len(tt.AllowedProjects) == 0 {
+		return true
+	}
+	for _, p := range tt.AllowedProjects {
+		if p.ID == projectID {
+			return true
+		}
+	}
+	return false
+}
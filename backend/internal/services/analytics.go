@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// queueAnalyticsRefresh 在事务中登记需要重新计算分析数据的范围
+// 只写入一条队列记录，计算由 RunAnalyticsWorker 在事务提交后进行，不占用上传事务的时间和锁
+func queueAnalyticsRefresh(tx *gorm.DB, projectID uint64, branch, testType string) error {
+	refresh := models.AnalyticsRefresh{
+		ProjectID:   projectID,
+		BranchName:  branch,
+		TestType:    testType,
+		Version:     1,
+		RequestedAt: time.Now(),
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"version":      gorm.Expr("version + 1"),
+			"requested_at": refresh.RequestedAt,
+		}),
+	}).Create(&refresh).Error; err != nil {
+		return fmt.Errorf("failed to queue analytics refresh: %w", err)
+	}
+	return nil
+}
+
+// queueTestRunAnalytics 测试运行参与分析（有测例结果的 passed / failed 运行）时登记其所在范围
+// 公开运行结束、运行的可见性变化或被删除时都会改变分析结果
+func queueTestRunAnalytics(tx *gorm.DB, testRun *models.TestRun) error {
+	if testRun.Status != models.TestRunStatusPassed && testRun.Status != models.TestRunStatusFailed {
+		return nil
+	}
+	return queueAnalyticsRefresh(tx, testRun.ProjectID, testRun.BranchName, testRun.TestType)
+}
+
+// queueProjectAnalytics 登记项目下所有参与分析的分支和测试类型，用于项目的分析配置（如 flaky_window_runs）变化后重新计算
+func queueProjectAnalytics(tx *gorm.DB, projectID uint64) error {
+	var scopes []struct {
+		BranchName string
+		TestType   string
+	}
+	if err := tx.Model(&models.TestRun{}).
+		Distinct("branch_name", "test_type").
+		Where("project_id = ? AND is_public = ? AND status IN ?",
+			projectID, true, []models.TestRunStatus{models.TestRunStatusPassed, models.TestRunStatusFailed}).
+		Scan(&scopes).Error; err != nil {
+		return fmt.Errorf("failed to get project analytics scopes: %w", err)
+	}
+	for _, scope := range scopes {
+		if err := queueAnalyticsRefresh(tx, projectID, scope.BranchName, scope.TestType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunAnalyticsWorker 定期处理登记的分析数据更新，直到 ctx 结束
+func RunAnalyticsWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			processed, err := ProcessAnalyticsRefreshes()
+			if err != nil {
+				logger.LogError(nil, logger.ModuleService, err, "process_analytics_refreshes failed")
+				continue
+			}
+			if processed > 0 {
+				logger.LogInfo(nil, logger.ModuleService, "process_analytics_refreshes processed=%d", processed)
+			}
+		}
+	}
+}
+
+// ProcessAnalyticsRefreshes 按登记顺序处理所有等待中的分析数据更新，返回处理完成的范围数量
+// 单个范围失败时记录日志并保留登记，下次再处理
+func ProcessAnalyticsRefreshes() (int, error) {
+	db := getDB(nil)
+
+	var refreshes []models.AnalyticsRefresh
+	if err := db.Order("requested_at ASC, id ASC").Find(&refreshes).Error; err != nil {
+		return 0, fmt.Errorf("failed to get analytics refreshes: %w", err)
+	}
+
+	processed := 0
+	for _, refresh := range refreshes {
+		if err := refreshAnalyticsScope(db, &refresh); err != nil {
+			logger.LogError(nil, logger.ModuleService, err, "refresh_analytics_scope failed project_id=%d branch=%s test_type=%s",
+				refresh.ProjectID, refresh.BranchName, refresh.TestType)
+			continue
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// refreshAnalyticsScope 重新计算一个范围的分析数据，完成后删除登记
+// 不稳定度在单独的事务中计算；master 的回归检测会跳过不稳定测例，需要在不稳定度更新之后进行。
+// 处理期间该范围又被登记（Version 变化）时保留记录，下次重新计算
+func refreshAnalyticsScope(db *gorm.DB, refresh *models.AnalyticsRefresh) error {
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return refreshFlakyScores(tx, refresh.ProjectID, refresh.BranchName, refresh.TestType)
+	}); err != nil {
+		return err
+	}
+	if refresh.BranchName == models.MasterBranch {
+		if err := refreshRegressions(db, refresh.ProjectID, refresh.TestType); err != nil {
+			return err
+		}
+	}
+
+	if err := db.Where("id = ? AND version = ?", refresh.ID, refresh.Version).
+		Delete(&models.AnalyticsRefresh{}).Error; err != nil {
+		return fmt.Errorf("failed to delete analytics refresh: %w", err)
+	}
+	return nil
+}
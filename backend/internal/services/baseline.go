@@ -0,0 +1,125 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 基线运行的匹配方式
+const (
+	BaselineMatchExact   = "exact"   // 基线运行的提交就是 PR 的合并基点
+	BaselineMatchEarlier = "earlier" // 合并基点没有可用的运行，使用 master 上更早的最近一次运行
+)
+
+// BaselineComparison PR 运行与 master 上基线运行的比较结果
+type BaselineComparison struct {
+	TestRun       *models.TestRun  `json:"test_run"`
+	Baseline      *models.TestRun  `json:"baseline"`
+	BaselineMatch string           `json:"baseline_match"`
+	NewlyFailing  []TestCaseChange `json:"newly_failing"` // 基线中未失败、PR 运行中失败的测例
+	NewlyPassing  []TestCaseChange `json:"newly_passing"` // 基线中失败、PR 运行中通过的测例
+	NewCases      []TestCaseChange `json:"new_cases"`     // 只在 PR 运行中出现的测例
+	RemovedCases  []TestCaseChange `json:"removed_cases"` // 只在基线中出现的测例
+}
+
+// CompareWithBaseline 将 PR 运行与其合并基点在 master 上的基线运行比较
+// 优先使用合并基点本身的运行；没有时使用 master 上合并基点之前最近的一次运行。
+// 基线只从同一项目、同一测试类型的公开且已完成（passed / failed）的 master 运行中选择
+func CompareWithBaseline(c *gin.Context, testRun *models.TestRun) (*BaselineComparison, error) {
+	if testRun.BaseCommitID == "" {
+		return nil, ErrNoBaseCommit
+	}
+
+	db := getDB(c)
+	baseline, match, err := findBaselineRun(db, testRun)
+	if err != nil {
+		return nil, err
+	}
+
+	baseCases, err := loadCaseSnapshots(db, baseline.ID)
+	if err != nil {
+		return nil, err
+	}
+	headCases, err := loadCaseSnapshots(db, testRun.ID)
+	if err != nil {
+		return nil, err
+	}
+	diff := diffTestCases(baseCases, headCases)
+
+	comparison := &BaselineComparison{
+		TestRun:       testRun,
+		Baseline:      baseline,
+		BaselineMatch: match,
+		NewlyFailing: filterChanges(diff.StatusChanged, func(tc TestCaseChange) bool {
+			return tc.HeadStatus == models.TestCaseStatusFailed
+		}),
+		NewlyPassing: filterChanges(diff.StatusChanged, func(tc TestCaseChange) bool {
+			return tc.BaseStatus == models.TestCaseStatusFailed && tc.HeadStatus == models.TestCaseStatusPassed
+		}),
+		NewCases:     diff.Added,
+		RemovedCases: diff.Removed,
+	}
+	return comparison, nil
+}
+
+// findBaselineRun 查找 PR 运行的基线运行
+func findBaselineRun(db *gorm.DB, testRun *models.TestRun) (*models.TestRun, string, error) {
+	candidates := func() *gorm.DB {
+		return db.Model(&models.TestRun{}).Preload("Commit").
+			Where("test_runs.project_id = ? AND test_runs.test_type = ? AND test_runs.branch_name = ? AND test_runs.is_public = ? AND test_runs.id <> ?",
+				testRun.ProjectID, testRun.TestType, models.MasterBranch, true, testRun.ID).
+			Where("test_runs.status IN ?", []models.TestRunStatus{models.TestRunStatusPassed, models.TestRunStatusFailed})
+	}
+
+	// 合并基点本身的运行，基点可以是完整或缩写的提交哈希
+	var baseline models.TestRun
+	err := candidates().
+		Where("test_runs.commit_id LIKE ?", testRun.BaseCommitID+"%").
+		Order("test_runs.created_at DESC").
+		First(&baseline).Error
+	if err == nil {
+		return &baseline, BaselineMatchExact, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", fmt.Errorf("failed to find baseline run: %w", err)
+	}
+
+	// 已知合并基点的提交时间时，按提交时间查找 master 上更早的最近一次运行
+	var baseCommit models.Commit
+	err = db.Where("sha LIKE ? AND committed_at IS NOT NULL", testRun.BaseCommitID+"%").
+		Order("committed_at DESC").
+		First(&baseCommit).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", fmt.Errorf("failed to get base commit: %w", err)
+	}
+	if err == nil {
+		err = candidates().
+			Joins("JOIN commits ON commits.sha = test_runs.commit_id").
+			Where("commits.committed_at <= ?", *baseCommit.CommittedAt).
+			Order("commits.committed_at DESC, test_runs.created_at DESC").
+			First(&baseline).Error
+		if err == nil {
+			return &baseline, BaselineMatchEarlier, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", fmt.Errorf("failed to find baseline run: %w", err)
+		}
+	}
+
+	// 否则使用 PR 运行开始之前 master 上最近的一次运行
+	err = candidates().
+		Where("test_runs.created_at <= ?", testRun.CreatedAt).
+		Order("test_runs.created_at DESC").
+		First(&baseline).Error
+	if err == nil {
+		return &baseline, BaselineMatchEarlier, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrBaselineNotFound
+	}
+	return nil, "", fmt.Errorf("failed to find baseline run: %w", err)
+}
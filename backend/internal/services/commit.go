@@ -0,0 +1,174 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// MaxCommitAuthorLength 提交作者的最大长度
+	MaxCommitAuthorLength = 255
+	// MaxCommitTitleLength 提交标题的最大长度
+	MaxCommitTitleLength = 500
+	// MaxCommitParents 父提交的最大数量
+	MaxCommitParents = 16
+	// commitDetailRunLimit 提交详情中最多返回的测试运行数量
+	commitDetailRunLimit = 100
+)
+
+// commitSHARe 匹配完整或缩写的提交哈希
+var commitSHARe = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// CommitInput 上传测试结果时附带的提交信息，所有字段都是可选的
+type CommitInput struct {
+	Author      string     `json:"author,omitempty"`
+	Title       string     `json:"title,omitempty"` // 提交信息，多行时只保存第一行
+	CommittedAt *time.Time `json:"committed_at,omitempty"`
+	Parents     []string   `json:"parents,omitempty"`
+	PRNumber    *uint32    `json:"pr_number,omitempty"`
+}
+
+// CommitQueryParams 提交搜索参数
+type CommitQueryParams struct {
+	Author   string // 作者（模糊匹配）
+	Query    string // 提交标题（模糊匹配）
+	PRNumber *uint32
+	Page     int
+	PageSize int
+}
+
+// validateCommitInput 校验提交信息，提交标题只保留第一行
+func validateCommitInput(input *CommitInput) error {
+	if input == nil {
+		return nil
+	}
+	input.Author = strings.TrimSpace(input.Author)
+	if line, _, found := strings.Cut(input.Title, "\n"); found {
+		input.Title = line
+	}
+	input.Title = strings.TrimSpace(input.Title)
+
+	if utf8.RuneCountInString(input.Author) > MaxCommitAuthorLength {
+		return fmt.Errorf("commit author exceeds maximum length of %d characters", MaxCommitAuthorLength)
+	}
+	if utf8.RuneCountInString(input.Title) > MaxCommitTitleLength {
+		return fmt.Errorf("commit title exceeds maximum length of %d characters", MaxCommitTitleLength)
+	}
+	if len(input.Parents) > MaxCommitParents {
+		return fmt.Errorf("too many parent commits (maximum %d)", MaxCommitParents)
+	}
+	for _, parent := range input.Parents {
+		if !commitSHARe.MatchString(parent) {
+			return fmt.Errorf("invalid parent commit '%s'", parent)
+		}
+	}
+	return nil
+}
+
+// ensureCommit 创建提交记录，已存在时保留原有记录
+// 提交记录由所有项目共享，不允许后续上传（可能来自其他项目的 API Key）覆盖已有的提交信息
+func ensureCommit(tx *gorm.DB, sha string, input *CommitInput) error {
+	commit := models.Commit{
+		SHA:      sha,
+		ShortSHA: shortCommitID(sha),
+	}
+	if input != nil {
+		commit.Author = input.Author
+		commit.Title = input.Title
+		commit.CommittedAt = input.CommittedAt
+		commit.ParentSHAs = strings.Join(input.Parents, ",")
+		commit.PRNumber = input.PRNumber
+	}
+
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "sha"}}, DoNothing: true}).
+		Create(&commit).Error; err != nil {
+		return fmt.Errorf("failed to save commit: %w", err)
+	}
+	return nil
+}
+
+// publicCommitRuns 只匹配至少有一个公开测试运行的提交，私有运行的提交信息不对外公开
+func publicCommitRuns(db *gorm.DB) *gorm.DB {
+	return db.Where("EXISTS (SELECT 1 FROM test_runs WHERE test_runs.commit_id = commits.sha AND test_runs.is_public = ?)", true)
+}
+
+// GetCommitBySHA 根据完整或缩写的提交哈希获取提交信息及其公开的测试运行
+// 缩写匹配到多个提交时返回 ErrAmbiguousCommit
+func GetCommitBySHA(c *gin.Context, sha string) (*models.Commit, error) {
+	if !commitSHARe.MatchString(sha) {
+		return nil, fmt.Errorf("%w: %s", ErrCommitNotFound, sha)
+	}
+
+	var commits []models.Commit
+	db := getDB(c)
+	if err := db.Model(&models.Commit{}).Scopes(publicCommitRuns).
+		Where("sha LIKE ?", sha+"%").
+		Order("sha ASC").
+		Limit(2).
+		Find(&commits).Error; err != nil {
+		return nil, fmt.Errorf("failed to get commit: %w", err)
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCommitNotFound, sha)
+	}
+	commit := commits[0]
+	if len(commits) > 1 && !strings.EqualFold(commit.SHA, sha) {
+		return nil, fmt.Errorf("%w: %s", ErrAmbiguousCommit, sha)
+	}
+
+	if err := db.Preload("Project").Preload("Metadata").
+		Where("commit_id = ? AND is_public = ?", commit.SHA, true).
+		Order("created_at DESC").
+		Limit(commitDetailRunLimit).
+		Find(&commit.TestRuns).Error; err != nil {
+		return nil, fmt.Errorf("failed to get test runs of commit: %w", err)
+	}
+	return &commit, nil
+}
+
+// SearchCommits 按作者或提交标题搜索提交，只返回有公开测试运行的提交，最新的提交排在前面
+func SearchCommits(c *gin.Context, params CommitQueryParams) ([]models.Commit, int64, error) {
+	var commits []models.Commit
+	var total int64
+
+	db := getDB(c)
+	query := db.Model(&models.Commit{}).Scopes(publicCommitRuns)
+	if params.Author != "" {
+		query = query.Where("author LIKE ?", "%"+params.Author+"%")
+	}
+	if params.Query != "" {
+		query = query.Where("title LIKE ?", "%"+params.Query+"%")
+	}
+	if params.PRNumber != nil {
+		query = query.Where("pr_number = ?", *params.PRNumber)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count commits: %w", err)
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	if err := query.Order("COALESCE(committed_at, created_at) DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&commits).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to search commits: %w", err)
+	}
+	return commits, total, nil
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 测试运行差异的分类
+const (
+	DiffCategoryAdded           = "added"            // 只在目标运行中出现的测例
+	DiffCategoryRemoved         = "removed"          // 只在基准运行中出现的测例
+	DiffCategoryNewlyFailing    = "newly_failing"    // 状态变为 failed（如 passed→failed）
+	DiffCategoryNewlyPassing    = "newly_passing"    // 状态变为 passed（如 failed→passed）
+	DiffCategoryNewlySkipped    = "newly_skipped"    // 状态变为 skipped
+	DiffCategoryDurationChanges = "duration_changes" // 耗时变化超过阈值
+)
+
+const (
+	// DefaultDiffDurationFactor 默认的耗时变化倍数阈值
+	DefaultDiffDurationFactor = 2.0
+	// DefaultDiffMinDurationDeltaMs 默认的耗时变化最小差值（毫秒），避免很短的测例因抖动被标记
+	DefaultDiffMinDurationDeltaMs = 100
+)
+
+// TestRunDiffParams 测试运行差异查询参数
+type TestRunDiffParams struct {
+	Category           string
+	DurationFactor     float64 // 耗时变为原来的 DurationFactor 倍以上或 1/DurationFactor 以下时视为变化
+	MinDurationDeltaMs uint32
+	Page               int
+	PageSize           int
+}
+
+// TestRunDiffCounts 各分类的测例数量
+type TestRunDiffCounts struct {
+	Added           int `json:"added"`
+	Removed         int `json:"removed"`
+	NewlyFailing    int `json:"newly_failing"`
+	NewlyPassing    int `json:"newly_passing"`
+	NewlySkipped    int `json:"newly_skipped"`
+	DurationChanges int `json:"duration_changes"`
+	Unchanged       int `json:"unchanged"` // 两次运行中状态相同的测例
+}
+
+// TestRunDiff 两次测试运行的差异，Changes 为所选分类的一页
+type TestRunDiff struct {
+	Base     *models.TestRun   `json:"base"`
+	Head     *models.TestRun   `json:"head"`
+	Counts   TestRunDiffCounts `json:"counts"`
+	Category string            `json:"category"`
+	Changes  []TestCaseChange  `json:"changes"`
+	Total    int               `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+}
+
+// caseSnapshot 比较两次测试运行时使用的测例字段
+type caseSnapshot struct {
+	Name       string
+	Status     models.TestCaseStatus
+	DurationMs uint32
+}
+
+// TestCaseChange 两次测试运行之间单个测例的变化
+// 新增的测例没有 base_status，被移除的测例没有 head_status
+type TestCaseChange struct {
+	Name           string                `json:"name"`
+	BaseStatus     models.TestCaseStatus `json:"base_status,omitempty"`
+	HeadStatus     models.TestCaseStatus `json:"head_status,omitempty"`
+	BaseDurationMs uint32                `json:"base_duration_ms,omitempty"`
+	HeadDurationMs uint32                `json:"head_duration_ms,omitempty"`
+
+	DurationDeltaMs int64 `json:"duration_delta_ms,omitempty"` // 只在耗时变化列表中返回
+}
+
+// testCaseDiff 两次测试运行的测例差异，各列表按测例名称排序
+type testCaseDiff struct {
+	Added         []TestCaseChange
+	Removed       []TestCaseChange
+	StatusChanged []TestCaseChange
+	Unchanged     []TestCaseChange
+}
+
+// loadCaseSnapshots 加载测试运行的所有测例，按名称索引
+func loadCaseSnapshots(db *gorm.DB, testRunID uint64) (map[string]caseSnapshot, error) {
+	var rows []caseSnapshot
+	if err := db.Model(&models.TestCase{}).
+		Select("name, status, duration_ms").
+		Where("test_run_id = ?", testRunID).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load test cases of test run %d: %w", testRunID, err)
+	}
+
+	snapshots := make(map[string]caseSnapshot, len(rows))
+	for _, row := range rows {
+		snapshots[row.Name] = row
+	}
+	return snapshots, nil
+}
+
+// diffTestCases 逐个测例比较基准运行（base）和目标运行（head）
+func diffTestCases(base, head map[string]caseSnapshot) testCaseDiff {
+	diff := testCaseDiff{
+		Added:         []TestCaseChange{},
+		Removed:       []TestCaseChange{},
+		StatusChanged: []TestCaseChange{},
+		Unchanged:     []TestCaseChange{},
+	}
+	for name, h := range head {
+		b, ok := base[name]
+		change := TestCaseChange{
+			Name:           name,
+			HeadStatus:     h.Status,
+			HeadDurationMs: h.DurationMs,
+		}
+		if !ok {
+			diff.Added = append(diff.Added, change)
+			continue
+		}
+		change.BaseStatus = b.Status
+		change.BaseDurationMs = b.DurationMs
+		if b.Status != h.Status {
+			diff.StatusChanged = append(diff.StatusChanged, change)
+		} else {
+			diff.Unchanged = append(diff.Unchanged, change)
+		}
+	}
+	for name, b := range base {
+		if _, ok := head[name]; !ok {
+			diff.Removed = append(diff.Removed, TestCaseChange{
+				Name:           name,
+				BaseStatus:     b.Status,
+				BaseDurationMs: b.DurationMs,
+			})
+		}
+	}
+
+	for _, changes := range [][]TestCaseChange{diff.Added, diff.Removed, diff.StatusChanged, diff.Unchanged} {
+		sortChangesByName(changes)
+	}
+	return diff
+}
+
+// sortChangesByName 按测例名称排序
+func sortChangesByName(changes []TestCaseChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+}
+
+// filterChanges 返回满足条件的测例变化
+func filterChanges(changes []TestCaseChange, keep func(TestCaseChange) bool) []TestCaseChange {
+	result := []TestCaseChange{}
+	for _, change := range changes {
+		if keep(change) {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// DiffTestRuns 逐个测例比较两次测试运行，返回各分类的数量和所选分类的一页变化
+// 分类不合法或耗时阈值不合法时返回 ErrInvalidDiffParams
+func DiffTestRuns(c *gin.Context, base, head *models.TestRun, params TestRunDiffParams) (*TestRunDiff, error) {
+	if params.Category == "" {
+		params.Category = DiffCategoryNewlyFailing
+	}
+	if params.DurationFactor == 0 {
+		params.DurationFactor = DefaultDiffDurationFactor
+	}
+	if params.DurationFactor <= 1 {
+		return nil, fmt.Errorf("%w: duration factor must be greater than 1", ErrInvalidDiffParams)
+	}
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = 20
+	}
+
+	db := getDB(c)
+	baseCases, err := loadCaseSnapshots(db, base.ID)
+	if err != nil {
+		return nil, err
+	}
+	headCases, err := loadCaseSnapshots(db, head.ID)
+	if err != nil {
+		return nil, err
+	}
+	diff := diffTestCases(baseCases, headCases)
+
+	categories := map[string][]TestCaseChange{
+		DiffCategoryAdded:   diff.Added,
+		DiffCategoryRemoved: diff.Removed,
+		DiffCategoryNewlyFailing: filterChanges(diff.StatusChanged, func(tc TestCaseChange) bool {
+			return tc.HeadStatus == models.TestCaseStatusFailed
+		}),
+		DiffCategoryNewlyPassing: filterChanges(diff.StatusChanged, func(tc TestCaseChange) bool {
+			return tc.HeadStatus == models.TestCaseStatusPassed
+		}),
+		DiffCategoryNewlySkipped: filterChanges(diff.StatusChanged, func(tc TestCaseChange) bool {
+			return tc.HeadStatus == models.TestCaseStatusSkipped
+		}),
+		DiffCategoryDurationChanges: durationChanges(append(diff.StatusChanged, diff.Unchanged...), params.DurationFactor, params.MinDurationDeltaMs),
+	}
+	changes, ok := categories[params.Category]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown category '%s'", ErrInvalidDiffParams, params.Category)
+	}
+
+	result := &TestRunDiff{
+		Base: base,
+		Head: head,
+		Counts: TestRunDiffCounts{
+			Added:           len(diff.Added),
+			Removed:         len(diff.Removed),
+			NewlyFailing:    len(categories[DiffCategoryNewlyFailing]),
+			NewlyPassing:    len(categories[DiffCategoryNewlyPassing]),
+			NewlySkipped:    len(categories[DiffCategoryNewlySkipped]),
+			DurationChanges: len(categories[DiffCategoryDurationChanges]),
+			Unchanged:       len(diff.Unchanged),
+		},
+		Category: params.Category,
+		Changes:  []TestCaseChange{},
+		Total:    len(changes),
+		Page:     params.Page,
+		PageSize: params.PageSize,
+	}
+	if offset := (params.Page - 1) * params.PageSize; offset < len(changes) {
+		end := offset + params.PageSize
+		if end > len(changes) {
+			end = len(changes)
+		}
+		result.Changes = changes[offset:end]
+	}
+	return result, nil
+}
+
+// durationChanges 返回耗时变化超过阈值的测例，变化最大的排在前面
+// 耗时较大的一方至少是较小一方的 factor 倍，且差值不小于 minDeltaMs
+func durationChanges(changes []TestCaseChange, factor float64, minDeltaMs uint32) []TestCaseChange {
+	result := []TestCaseChange{}
+	for _, tc := range changes {
+		delta := int64(tc.HeadDurationMs) - int64(tc.BaseDurationMs)
+		if delta == 0 || absInt64(delta) < int64(minDeltaMs) {
+			continue
+		}
+		shorter, longer := tc.BaseDurationMs, tc.HeadDurationMs
+		if shorter > longer {
+			shorter, longer = longer, shorter
+		}
+		if float64(longer) < float64(shorter)*factor {
+			continue
+		}
+		tc.DurationDeltaMs = delta
+		result = append(result, tc)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return absInt64(result[i].DurationDeltaMs) > absInt64(result[j].DurationDeltaMs)
+	})
+	return result
+}
+
+func absInt64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -5,9 +5,38 @@ import "errors"
 // 统一定义所有服务层错误
 var (
 	// 项目相关错误
-	ErrProjectExists   = errors.New("project with this name already exists")
-	ErrProjectNotFound = errors.New("project not found")
+	ErrProjectExists    = errors.New("project with this name already exists")
+	ErrProjectNotFound  = errors.New("project not found")
+	ErrProjectForbidden = errors.New("API key is not allowed to access this project")
 
 	// 测试运行相关错误
-	ErrTestRunNotFound = errors.New("test run not found")
+	ErrTestRunNotFound         = errors.New("test run not found")
+	ErrInvalidTestRunUpload    = errors.New("invalid test run upload")
+	ErrTestRunNotRunning       = errors.New("test run is not running")
+	ErrInvalidStatusTransition = errors.New("invalid test run status transition")
+	ErrIdempotencyKeyConflict  = errors.New("idempotency key was already used with a different payload")
+	ErrNoBaseCommit            = errors.New("test run has no base commit")
+	ErrBaselineNotFound        = errors.New("no baseline run found on master")
+	ErrInvalidDiffParams       = errors.New("invalid test run diff parameters")
+	ErrInvalidSlowdownParams   = errors.New("invalid slowdown parameters")
+	ErrInvalidTrendParams      = errors.New("invalid trend parameters")
+	ErrInvalidMetadataFilter   = errors.New("invalid metadata filter")
+
+	// 上传格式相关错误
+	ErrInvalidJUnitReport = errors.New("invalid JUnit XML report")
+	ErrInvalidGtestLog    = errors.New("invalid gtest log")
+
+	// 提交相关错误
+	ErrCommitNotFound  = errors.New("commit not found")
+	ErrAmbiguousCommit = errors.New("commit SHA prefix matches multiple commits")
+
+	// 测例相关错误
+	ErrTestCaseNotFound = errors.New("test case not found")
+	ErrInvalidLogKind   = errors.New("log kind must be 'error' or 'debug'")
+
+	// 测试类型相关错误
+	ErrTestTypeExists     = errors.New("test type with this name already exists")
+	ErrTestTypeNotFound   = errors.New("test type not found")
+	ErrTestTypeNotAllowed = errors.New("test type is not allowed for this project")
+	ErrTestTypeInUse      = errors.New("test type is used by existing test runs")
 )
@@ -11,12 +11,14 @@ import (
 	"github.com/dragonos/dragonos-ci-dashboard/internal/config"
 	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// SaveFile 保存文件
-func SaveFile(c *gin.Context, testRunID uint64, filename string, fileContent io.Reader) (*models.TestOutputFile, error) {
+// SaveFile 保存文件，testCaseID 不为空时文件关联到该测例
+// 测试运行仍在运行时同时记录活动时间，避免上传文件期间被自动取消
+func SaveFile(c *gin.Context, testRunID uint64, testCaseID *uint64, filename string, fileContent io.Reader) (*models.TestOutputFile, error) {
 	// 创建测试运行的文件目录
-	fileDir := filepath.Join(config.AppConfig.Storage.Path, fmt.Sprintf("test_run_%d", testRunID))
+	fileDir := testRunStorageDir(testRunID)
 	if err := os.MkdirAll(fileDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create file directory: %w", err)
 	}
@@ -48,23 +50,43 @@ func SaveFile(c *gin.Context, testRunID uint64, filename string, fileContent io.
 
 	// 创建数据库记录
 	outputFile := &models.TestOutputFile{
-		TestRunID: testRunID,
-		Filename:  filename,
-		FilePath:  filePath,
-		FileSize:  uint64(fileSize),
-		MimeType:  mimeType,
+		TestRunID:  testRunID,
+		TestCaseID: testCaseID,
+		Filename:   filename,
+		FilePath:   filePath,
+		FileSize:   uint64(fileSize),
+		MimeType:   mimeType,
 	}
 
 	db := getDB(c)
-	if err := db.Create(outputFile).Error; err != nil {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(outputFile).Error; err != nil {
+			return fmt.Errorf("failed to create file record: %w", err)
+		}
+
+		testRun := models.TestRun{ID: testRunID}
+		testRun.Touch()
+		if err := tx.Model(&models.TestRun{}).
+			Where("id = ? AND status = ?", testRunID, models.TestRunStatusRunning).
+			Update("last_activity_at", testRun.LastActivityAt).Error; err != nil {
+			return fmt.Errorf("failed to update last activity: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
 		// 如果数据库保存失败，删除已创建的文件
 		os.Remove(filePath)
-		return nil, fmt.Errorf("failed to create file record: %w", err)
+		return nil, err
 	}
 
 	return outputFile, nil
 }
 
+// testRunStorageDir 测试运行的文件存储目录
+func testRunStorageDir(testRunID uint64) string {
+	return filepath.Join(config.AppConfig.Storage.Path, fmt.Sprintf("test_run_%d", testRunID))
+}
+
 // GetFileByID 根据ID获取文件
 func GetFileByID(c *gin.Context, id uint64) (*models.TestOutputFile, error) {
 	var file models.TestOutputFile
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// FlakyScoreThreshold 不稳定度达到该值（且状态变化足够多）时标记为不稳定
+	FlakyScoreThreshold = 0.1
+	// flakyMinEvents 只有相邻结果切换时，至少需要的状态变化次数
+	// 一次失败后修复只有两次切换，属于回归而不是不稳定
+	flakyMinEvents = 3
+)
+
+// FlakyTestQueryParams 不稳定测例查询参数
+type FlakyTestQueryParams struct {
+	ProjectID *uint64
+	Branch    string
+	TestType  string
+	FlakyOnly bool // 只返回被标记为不稳定的测例
+	Page      int
+	PageSize  int
+}
+
+// flakyObservation 测例在一次测试运行中的结果
+type flakyObservation struct {
+	TestRunID     uint64
+	Name          string
+	Status        models.TestCaseStatus
+	PassedOnRetry bool
+}
+
+// refreshFlakyScores 重新计算项目、分支和测试类型下所有测例的不稳定度，由分析任务在单独的事务中调用
+// 使用最近 flaky_window_runs 次公开且已完成（passed / failed）的测试运行：
+//   - flips：按时间顺序相邻两次结果（忽略跳过）在通过和失败之间切换的次数
+//   - same_commit_flips：同一提交的多次运行中既通过又失败、或失败后重试通过的次数
+//   - score = (flips + same_commit_flips) / runs，最大为1
+//
+// 有 same_commit_flips，或 score 不低于 FlakyScoreThreshold 且变化次数不少于 flakyMinEvents 时标记为不稳定
+func refreshFlakyScores(tx *gorm.DB, projectID uint64, branch, testType string) error {
+	// 锁定项目，避免多个服务实例同时计算同一项目
+	var project models.Project
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, projectID).Error; err != nil {
+		return fmt.Errorf("failed to lock project: %w", err)
+	}
+	window := project.FlakyWindowRuns
+	if window == 0 {
+		window = models.DefaultFlakyWindowRuns
+	}
+
+	var runs []models.TestRun
+	if err := analyzedTestRuns(tx, projectID, branch, testType).
+		Select("id, commit_id").
+		Order("created_at DESC, id DESC").
+		Limit(int(window)).
+		Find(&runs).Error; err != nil {
+		return fmt.Errorf("failed to get test runs for flaky detection: %w", err)
+	}
+
+	// 按时间顺序排列
+	runIDs := make([]uint64, len(runs))
+	runIndex := make(map[uint64]int, len(runs))
+	runCommit := make(map[uint64]string, len(runs))
+	for i := range runs {
+		run := runs[len(runs)-1-i]
+		runIDs[i] = run.ID
+		runIndex[run.ID] = i
+		runCommit[run.ID] = run.CommitID
+	}
+
+	var observations []flakyObservation
+	if len(runIDs) > 0 {
+		if err := tx.Model(&models.TestCase{}).
+			Select("test_run_id, name, status, passed_on_retry").
+			Where("test_run_id IN ? AND status <> ?", runIDs, models.TestCaseStatusSkipped).
+			Scan(&observations).Error; err != nil {
+			return fmt.Errorf("failed to get test cases for flaky detection: %w", err)
+		}
+	}
+	sort.SliceStable(observations, func(i, j int) bool {
+		return runIndex[observations[i].TestRunID] < runIndex[observations[j].TestRunID]
+	})
+
+	byName := make(map[string][]flakyObservation)
+	for _, o := range observations {
+		byName[o.Name] = append(byName[o.Name], o)
+	}
+
+	now := time.Now()
+	var entries []models.FlakyTestCase
+	for name, history := range byName {
+		entry := scoreFlakyHistory(history, runCommit)
+		if entry.Flips == 0 && entry.SameCommitFlips == 0 {
+			continue
+		}
+		entry.ProjectID = projectID
+		entry.BranchName = branch
+		entry.TestType = testType
+		entry.Name = name
+		entry.UpdatedAt = now
+		entries = append(entries, entry)
+	}
+
+	if err := tx.Where("project_id = ? AND branch_name = ? AND test_type = ?", projectID, branch, testType).
+		Delete(&models.FlakyTestCase{}).Error; err != nil {
+		return fmt.Errorf("failed to clear flaky test cases: %w", err)
+	}
+	if len(entries) > 0 {
+		if err := tx.CreateInBatches(entries, 500).Error; err != nil {
+			return fmt.Errorf("failed to save flaky test cases: %w", err)
+		}
+	}
+	return nil
+}
+
+// scoreFlakyHistory 根据测例按时间顺序排列的结果计算不稳定度
+func scoreFlakyHistory(history []flakyObservation, runCommit map[uint64]string) models.FlakyTestCase {
+	var entry models.FlakyTestCase
+	commitStatuses := make(map[string]map[models.TestCaseStatus]bool)
+	for i, o := range history {
+		entry.Runs++
+		if i > 0 && o.Status != history[i-1].Status {
+			entry.Flips++
+		}
+		if o.PassedOnRetry {
+			entry.SameCommitFlips++
+		}
+		if o.Status == models.TestCaseStatusFailed {
+			runID := o.TestRunID
+			entry.LastFailedRunID = &runID
+		}
+
+		commit := runCommit[o.TestRunID]
+		if commitStatuses[commit] == nil {
+			commitStatuses[commit] = make(map[models.TestCaseStatus]bool)
+		}
+		commitStatuses[commit][o.Status] = true
+	}
+	for _, statuses := range commitStatuses {
+		if statuses[models.TestCaseStatusPassed] && statuses[models.TestCaseStatusFailed] {
+			entry.SameCommitFlips++
+		}
+	}
+
+	events := entry.Flips + entry.SameCommitFlips
+	if entry.Runs > 0 {
+		entry.Score = float64(events) / float64(entry.Runs)
+		if entry.Score > 1 {
+			entry.Score = 1
+		}
+	}
+	entry.IsFlaky = entry.SameCommitFlips > 0 ||
+		(entry.Score >= FlakyScoreThreshold && events >= flakyMinEvents)
+	return entry
+}
+
+// QueryFlakyTests 按不稳定度从高到低列出测例
+func QueryFlakyTests(c *gin.Context, params FlakyTestQueryParams) ([]models.FlakyTestCase, int64, error) {
+	var entries []models.FlakyTestCase
+	var total int64
+
+	db := getDB(c)
+	query := db.Model(&models.FlakyTestCase{})
+	if params.ProjectID != nil {
+		query = query.Where("project_id = ?", *params.ProjectID)
+	}
+	if params.Branch != "" {
+		query = query.Where("branch_name = ?", params.Branch)
+	}
+	if params.TestType != "" {
+		query = query.Where("test_type = ?", params.TestType)
+	}
+	if params.FlakyOnly {
+		query = query.Where("is_flaky = ?", true)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count flaky test cases: %w", err)
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	if err := query.Order("score DESC, same_commit_flips DESC, name ASC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&entries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to query flaky test cases: %w", err)
+	}
+	return entries, total, nil
+}
+
+// markFlakyTestCases 根据测试运行所在项目、分支和测试类型的不稳定度标记测例
+// PR 等分支的运行次数很少，分支上没有不稳定度记录的测例使用同一项目和测试类型在 master 上的不稳定度
+func markFlakyTestCases(db *gorm.DB, testRunID uint64, testCases []models.TestCase) error {
+	if len(testCases) == 0 {
+		return nil
+	}
+
+	var testRun models.TestRun
+	if err := db.Select("id, project_id, branch_name, test_type").First(&testRun, testRunID).Error; err != nil {
+		return fmt.Errorf("failed to get test run: %w", err)
+	}
+
+	var entries []models.FlakyTestCase
+	if err := db.Select("name, branch_name, score, is_flaky").
+		Where("project_id = ? AND branch_name IN ? AND test_type = ?",
+			testRun.ProjectID, []string{testRun.BranchName, models.MasterBranch}, testRun.TestType).
+		Find(&entries).Error; err != nil {
+		return fmt.Errorf("failed to get flaky test cases: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	byName := make(map[string]models.FlakyTestCase, len(entries))
+	for _, e := range entries {
+		if _, ok := byName[e.Name]; ok && e.BranchName != testRun.BranchName {
+			continue
+		}
+		byName[e.Name] = e
+	}
+	for i := range testCases {
+		if e, ok := byName[testCases[i].Name]; ok {
+			testCases[i].IsFlaky = e.IsFlaky
+			testCases[i].FlakyScore = e.Score
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+)
+
+// gtestMaxLineLength 单行日志的最大长度
+const gtestMaxLineLength = 16 * 1024 * 1024
+
+var (
+	// gtestMarkerRe 匹配 [ RUN      ] / [       OK ] / [  FAILED  ] / [  SKIPPED ] 行
+	// 不要求行首匹配，串口日志中常带有时间戳等前缀
+	gtestMarkerRe = regexp.MustCompile(`\[\s*(RUN|OK|FAILED|SKIPPED)\s*\]\s+(.*)$`)
+	// gtestSeparatorRe 匹配 [==========] / [----------] 分隔行，出现时说明上一个测例已经结束输出
+	gtestSeparatorRe = regexp.MustCompile(`\[(=+|-+)\]`)
+	// gtestDurationRe 匹配行尾的耗时，如 (12 ms)
+	gtestDurationRe = regexp.MustCompile(`\((\d+)\s*ms\)\s*$`)
+	// ansiEscapeRe 匹配终端颜色控制符
+	ansiEscapeRe = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+)
+
+// gtestOpenCase 已经输出 [ RUN ] 但尚未结束的测例
+type gtestOpenCase struct {
+	name   string
+	output []string
+}
+
+// ParseGtestLog 解析gVisor系统调用测试（gtest格式）的原始控制台输出，转换为测例上传数据
+// 测例在 [ RUN ] 和 [ OK ] / [ FAILED ] / [ SKIPPED ] 之间的输出作为该测例的日志；
+// 只有 [ RUN ] 没有结束标记的测例（如运行时崩溃）视为失败；失败重试产生的同名测例合并为多次尝试
+func ParseGtestLog(r io.Reader) ([]TestCaseInput, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), gtestMaxLineLength)
+
+	var testCases []TestCaseInput
+	var current *gtestOpenCase
+
+	for scanner.Scan() {
+		line := strings.TrimRight(ansiEscapeRe.ReplaceAllString(scanner.Text(), ""), "\r")
+
+		m := gtestMarkerRe.FindStringSubmatch(line)
+		if m == nil {
+			if current != nil && gtestSeparatorRe.MatchString(line) {
+				testCases = append(testCases, current.unfinished())
+				current = nil
+			} else if current != nil {
+				current.output = append(current.output, line)
+			}
+			continue
+		}
+
+		marker, rest := m[1], m[2]
+		name := gtestCaseName(rest)
+		if name == "" {
+			continue
+		}
+
+		if marker == "RUN" {
+			if current != nil {
+				testCases = append(testCases, current.unfinished())
+			}
+			current = &gtestOpenCase{name: name}
+			continue
+		}
+
+		// 结束标记与当前测例不匹配时为汇总信息（如末尾的失败列表），直接忽略
+		if current == nil || name != current.name {
+			continue
+		}
+
+		tc := TestCaseInput{Name: current.name}
+		if dm := gtestDurationRe.FindStringSubmatch(rest); dm != nil {
+			if ms, err := strconv.ParseUint(dm[1], 10, 32); err == nil {
+				tc.DurationMs = uint32(ms)
+			}
+		}
+		output := strings.TrimSpace(strings.Join(current.output, "\n"))
+		switch marker {
+		case "OK":
+			tc.Status = models.TestCaseStatusPassed
+		case "FAILED":
+			tc.Status = models.TestCaseStatusFailed
+			tc.ErrorLog = output
+		case "SKIPPED":
+			tc.Status = models.TestCaseStatusSkipped
+			tc.DebugLog = output
+		}
+		testCases = append(testCases, tc)
+		current = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGtestLog, err)
+	}
+
+	if current != nil {
+		testCases = append(testCases, current.unfinished())
+	}
+	if len(testCases) == 0 {
+		return nil, fmt.Errorf("%w: no test cases found", ErrInvalidGtestLog)
+	}
+
+	return mergeRetriedCases(testCases), nil
+}
+
+// unfinished 将没有结束标记的测例转换为失败测例
+func (oc *gtestOpenCase) unfinished() TestCaseInput {
+	output := strings.TrimSpace(strings.Join(oc.output, "\n"))
+	errorLog := "test did not finish (runner crashed or output was truncated)"
+	if output != "" {
+		errorLog = output + "\n" + errorLog
+	}
+	return TestCaseInput{
+		Name:     oc.name,
+		Status:   models.TestCaseStatusFailed,
+		ErrorLog: errorLog,
+	}
+}
+
+// gtestCaseName 从标记后的内容中提取测例名称
+// 参数化测例失败时格式为 "Suite.Test/0, where GetParam() = ... (5 ms)"
+func gtestCaseName(rest string) string {
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(fields[0], ",")
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+)
+
+// gvisorGtestLog gVisor 系统调用测试的控制台输出（节选），带有串口时间戳前缀和终端颜色控制符
+const gvisorGtestLog = "[==========] Running 5 tests from 3 test suites.\n" +
+	"[----------] Global test environment set-up.\n" +
+	"[----------] 2 tests from ReadTest\n" +
+	"[  12.345678] \x1b[0;32m[ RUN      ] \x1b[mReadTest.ReadEmptyFile\r\n" +
+	"[  12.357890] \x1b[0;32m[       OK ] \x1b[mReadTest.ReadEmptyFile (12 ms)\r\n" +
+	"[ RUN      ] ReadTest.ReadWithOffset\n" +
+	"test/syscalls/linux/read.cc:52: Failure\n" +
+	"Value of: read(fd, buf, 1)\n" +
+	"Expected: 1\n" +
+	"  Actual: -1 (of type long)\n" +
+	"[  FAILED  ] ReadTest.ReadWithOffset (8 ms)\n" +
+	"[----------] 2 tests from ReadTest (20 ms total)\n" +
+	"\n" +
+	"[----------] 1 test from SocketInetLoopbackTest/SocketInetReusePortTest\n" +
+	"[ RUN      ] SocketInetLoopbackTest/SocketInetReusePortTest.TcpPortReuseMultiThread/0\n" +
+	"[  FAILED  ] SocketInetLoopbackTest/SocketInetReusePortTest.TcpPortReuseMultiThread/0, where GetParam() = TestParam{V4Any, V4Loopback} (503 ms)\n" +
+	"[----------] 2 tests from EpollTest\n" +
+	"[ RUN      ] EpollTest.EdgeTriggered\n" +
+	"test/syscalls/linux/epoll.cc:88: Skipped\n" +
+	"Not supported on this platform\n" +
+	"[  SKIPPED ] EpollTest.EdgeTriggered (0 ms)\n" +
+	"[ RUN      ] EpollTest.Timeout\n" +
+	"[       OK ] EpollTest.Timeout (100 ms)\n" +
+	"[==========] 5 tests from 3 test suites ran. (623 ms total)\n" +
+	"[  PASSED  ] 2 tests.\n" +
+	"[  SKIPPED ] 1 test, listed below:\n" +
+	"[  SKIPPED ] EpollTest.EdgeTriggered\n" +
+	"[  FAILED  ] 2 tests, listed below:\n" +
+	"[  FAILED  ] ReadTest.ReadWithOffset\n" +
+	"[  FAILED  ] SocketInetLoopbackTest/SocketInetReusePortTest.TcpPortReuseMultiThread/0, where GetParam() = TestParam{V4Any, V4Loopback}\n"
+
+func TestParseGtestLog(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want []TestCaseInput
+	}{
+		{
+			name: "gvisor console output",
+			log:  gvisorGtestLog,
+			want: []TestCaseInput{
+				{Name: "ReadTest.ReadEmptyFile", Status: models.TestCaseStatusPassed, DurationMs: 12},
+				{
+					Name:       "ReadTest.ReadWithOffset",
+					Status:     models.TestCaseStatusFailed,
+					DurationMs: 8,
+					ErrorLog:   "test/syscalls/linux/read.cc:52: Failure\nValue of: read(fd, buf, 1)\nExpected: 1\n  Actual: -1 (of type long)",
+				},
+				{Name: "SocketInetLoopbackTest/SocketInetReusePortTest.TcpPortReuseMultiThread/0", Status: models.TestCaseStatusFailed, DurationMs: 503},
+				{
+					Name:     "EpollTest.EdgeTriggered",
+					Status:   models.TestCaseStatusSkipped,
+					DebugLog: "test/syscalls/linux/epoll.cc:88: Skipped\nNot supported on this platform",
+				},
+				{Name: "EpollTest.Timeout", Status: models.TestCaseStatusPassed, DurationMs: 100},
+			},
+		},
+		{
+			name: "truncated log marks the running test case as failed",
+			log: "[ RUN      ] MmapTest.MapZero\n" +
+				"[       OK ] MmapTest.MapZero (1 ms)\n" +
+				"[ RUN      ] MmapTest.MapHuge\n" +
+				"allocating 1GiB\n",
+			want: []TestCaseInput{
+				{Name: "MmapTest.MapZero", Status: models.TestCaseStatusPassed, DurationMs: 1},
+				{Name: "MmapTest.MapHuge", Status: models.TestCaseStatusFailed, ErrorLog: "allocating 1GiB\ntest did not finish (runner crashed or output was truncated)"},
+			},
+		},
+		{
+			name: "crash followed by the next test case",
+			log: "[ RUN      ] SignalTest.Abort\n" +
+				"[ RUN      ] SignalTest.Kill\n" +
+				"[       OK ] SignalTest.Kill (2 ms)\n",
+			want: []TestCaseInput{
+				{Name: "SignalTest.Abort", Status: models.TestCaseStatusFailed, ErrorLog: "test did not finish (runner crashed or output was truncated)"},
+				{Name: "SignalTest.Kill", Status: models.TestCaseStatusPassed, DurationMs: 2},
+			},
+		},
+		{
+			name: "crash detected at suite separator",
+			log: "[ RUN      ] ForkTest.Exec\n" +
+				"Segmentation fault\n" +
+				"[----------] 1 test from ForkTest (5 ms total)\n",
+			want: []TestCaseInput{
+				{Name: "ForkTest.Exec", Status: models.TestCaseStatusFailed, ErrorLog: "Segmentation fault\ntest did not finish (runner crashed or output was truncated)"},
+			},
+		},
+		{
+			name: "retried test case is merged into attempts",
+			log: "[ RUN      ] PipeTest.Flaky\n" +
+				"timeout\n" +
+				"[  FAILED  ] PipeTest.Flaky (30 ms)\n" +
+				"[ RUN      ] PipeTest.Flaky\n" +
+				"[       OK ] PipeTest.Flaky (10 ms)\n",
+			want: []TestCaseInput{
+				{Name: "PipeTest.Flaky", Attempts: []TestCaseAttemptInput{
+					{Status: models.TestCaseStatusFailed, DurationMs: 30, ErrorLog: "timeout"},
+					{Status: models.TestCaseStatusPassed, DurationMs: 10},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGtestLog(strings.NewReader(tt.log))
+			if err != nil {
+				t.Fatalf("ParseGtestLog() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGtestLog() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGtestLogInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+	}{
+		{name: "empty log", log: ""},
+		{name: "no test cases", log: "[==========] Running 0 tests from 0 test suites.\n[  PASSED  ] 0 tests.\n"},
+		{name: "line too long", log: "[ RUN      ] A.B\n" + strings.Repeat("x", gtestMaxLineLength+1) + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGtestLog(strings.NewReader(tt.log))
+			if !errors.Is(err, ErrInvalidGtestLog) {
+				t.Errorf("ParseGtestLog() error = %v, want %v", err, ErrInvalidGtestLog)
+			}
+		})
+	}
+}
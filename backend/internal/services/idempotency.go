@@ -0,0 +1,45 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MaxIdempotencyKeyLength 幂等键的最大长度
+const MaxIdempotencyKeyLength = 255
+
+// HashPayload 计算上传内容的哈希，用于判断同一幂等键的重放内容是否一致
+func HashPayload(payload interface{}) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// FindIdempotentTestRun 查找幂等键对应的已有测试运行
+// 没有记录时返回 nil；内容哈希不一致时返回 ErrIdempotencyKeyConflict
+func FindIdempotentTestRun(c *gin.Context, projectID uint64, idempotencyKey, payloadHash string) (*models.TestRun, error) {
+	var testRun models.TestRun
+	db := getDB(c)
+	if err := db.Where("project_id = ? AND idempotency_key = ?", projectID, idempotencyKey).
+		First(&testRun).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find test run by idempotency key: %w", err)
+	}
+
+	if testRun.PayloadHash != payloadHash {
+		return nil, fmt.Errorf("%w: test run %d", ErrIdempotencyKeyConflict, testRun.ID)
+	}
+	return &testRun, nil
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TestRunInput 测试运行上传数据，各种上传格式（JSON、JUnit、gtest日志）解析后统一转换为该结构
+type TestRunInput struct {
+	ProjectID      uint64
+	BranchName     string
+	CommitID       string
+	TestType       string
+	Status         string            // 为空时根据测例状态自动推断，为 running 时保持运行中
+	Metadata       map[string]string // 自定义元数据，如 runner_hostname、qemu_version、kvm
+	TestCases      []TestCaseInput
+	IdempotencyKey string `json:"-"` // 不参与内容哈希
+
+	// 提交信息，保存在同一提交的测试运行共享的提交记录中
+	Commit *CommitInput `json:",omitempty"`
+
+	// PR 运行：PR 编号和合并基点（master 上的提交），用于与 master 的基线运行比较
+	PRNumber     *uint32 `json:",omitempty"`
+	BaseCommitID string  `json:",omitempty"`
+
+	// 导入历史数据时的原始时间，为空时使用当前时间
+	StartedAt   *time.Time `json:",omitempty"`
+	CompletedAt *time.Time `json:",omitempty"`
+}
+
+// IngestTestRun 在一个事务中创建测试运行、写入测例并设置最终状态
+// 任何一步失败都会回滚，不会留下只有部分测例的运行记录
+// 幂等键重放时返回原有测试运行，replayed 为 true
+func IngestTestRun(c *gin.Context, input TestRunInput) (testRun *models.TestRun, replayed bool, err error) {
+	if err := CheckTestRunInput(c, &input); err != nil {
+		return nil, false, err
+	}
+
+	// 幂等上传：相同幂等键的重放直接返回原有测试运行
+	var payloadHash string
+	if input.IdempotencyKey != "" {
+		payloadHash, err = HashPayload(input)
+		if err != nil {
+			return nil, false, err
+		}
+		existing, err := FindIdempotentTestRun(c, input.ProjectID, input.IdempotencyKey, payloadHash)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing != nil {
+			return existing, true, nil
+		}
+	}
+
+	testRun = input.newTestRun()
+	if input.IdempotencyKey != "" {
+		testRun.IdempotencyKey = &input.IdempotencyKey
+		testRun.PayloadHash = payloadHash
+	}
+
+	var blobPaths []string
+	db := getDB(c)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := createTestRun(tx, testRun, &input); err != nil {
+			return err
+		}
+
+		paths, err := insertTestCases(tx, testRun, input.TestCases)
+		blobPaths = paths
+		if err != nil {
+			return err
+		}
+
+		var counts testCaseCounts
+		for _, tc := range input.TestCases {
+			counts.add(tc.Status)
+		}
+		return finalizeTestRun(tx, testRun, models.TestRunStatus(input.Status), counts, input.CompletedAt)
+	})
+	if err != nil {
+		removeFiles(blobPaths)
+		return recoverIdempotentIngest(c, input.ProjectID, input.IdempotencyKey, payloadHash, err)
+	}
+
+	logger.LogInfo(c, logger.ModuleService, "ingest_test_run completed test_run_id=%d test_cases_count=%d status=%s",
+		testRun.ID, len(input.TestCases), testRun.Status)
+
+	return testRun, false, nil
+}
+
+// createTestRun 写入提交记录、运行中的测试运行和元数据
+func createTestRun(tx *gorm.DB, testRun *models.TestRun, input *TestRunInput) error {
+	if err := ensureCommit(tx, input.CommitID, input.commitInput()); err != nil {
+		return err
+	}
+	if err := tx.Create(testRun).Error; err != nil {
+		return fmt.Errorf("failed to create test run: %w", err)
+	}
+	if len(input.Metadata) > 0 {
+		if err := tx.Create(buildTestRunMetadata(testRun.ID, input.Metadata)).Error; err != nil {
+			return fmt.Errorf("failed to create test run metadata: %w", err)
+		}
+	}
+	return nil
+}
+
+// finalizeTestRun 设置上传的最终状态
+// 显式指定 running 状态或没有测例且未指定状态时保持运行中，等待后续追加测例并结束；
+// 未指定状态时根据测例统计推断，completedAt 不为空时使用指定的完成时间（导入历史数据）
+func finalizeTestRun(tx *gorm.DB, testRun *models.TestRun, status models.TestRunStatus, counts testCaseCounts, completedAt *time.Time) error {
+	if status == models.TestRunStatusRunning || (status == "" && counts.total == 0) {
+		return nil
+	}
+	if status == "" {
+		project, err := loadProjectSettings(tx, testRun.ProjectID)
+		if err != nil {
+			return err
+		}
+		status = counts.derive(project.SkippedOnlyStatus)
+	}
+	if completedAt != nil {
+		return transitionTestRunAt(tx, testRun, status, *completedAt)
+	}
+	return transitionTestRun(tx, testRun, status)
+}
+
+// discardTestRun 删除写入失败的测试运行（测例等通过外键级联删除）及其已写入的日志文件
+func discardTestRun(db *gorm.DB, testRunID uint64, blobPaths []string) error {
+	removeFiles(blobPaths)
+	if err := db.Delete(&models.TestRun{}, testRunID).Error; err != nil {
+		return fmt.Errorf("failed to delete test run %d: %w", testRunID, err)
+	}
+	return nil
+}
+
+// recoverIdempotentIngest 写入失败时检查并发重试是否已经用相同幂等键和内容创建了测试运行，
+// 是则作为重放返回该测试运行，否则返回原错误
+func recoverIdempotentIngest(c *gin.Context, projectID uint64, idempotencyKey, payloadHash string, err error) (*models.TestRun, bool, error) {
+	if idempotencyKey == "" || payloadHash == "" || errors.Is(err, ErrInvalidTestRunUpload) {
+		return nil, false, err
+	}
+	existing, findErr := FindIdempotentTestRun(c, projectID, idempotencyKey, payloadHash)
+	if findErr != nil || existing != nil {
+		return existing, existing != nil, findErr
+	}
+	return nil, false, err
+}
+
+// newTestRun 根据上传数据创建运行中的测试运行（尚未写入数据库）
+func (input *TestRunInput) newTestRun() *models.TestRun {
+	testRun := &models.TestRun{
+		ProjectID:     input.ProjectID,
+		BranchName:    input.BranchName,
+		CommitID:      input.CommitID,
+		CommitShortID: shortCommitID(input.CommitID),
+		TestType:      input.TestType,
+		Status:        models.TestRunStatusRunning,
+		PRNumber:      input.PRNumber,
+		BaseCommitID:  input.BaseCommitID,
+	}
+	if input.StartedAt != nil {
+		testRun.StartedAt = input.StartedAt
+		testRun.CreatedAt = *input.StartedAt
+		testRun.LastActivityAt = input.StartedAt
+		if input.CompletedAt != nil {
+			testRun.LastActivityAt = input.CompletedAt
+		}
+	}
+	return testRun
+}
+
+// commitInput 返回要保存的提交信息，提交信息中没有 PR 编号时使用测试运行的 PR 编号
+func (input *TestRunInput) commitInput() *CommitInput {
+	if input.PRNumber == nil || (input.Commit != nil && input.Commit.PRNumber != nil) {
+		return input.Commit
+	}
+	commit := CommitInput{}
+	if input.Commit != nil {
+		commit = *input.Commit
+	}
+	commit.PRNumber = input.PRNumber
+	return &commit
+}
+
+// CheckTestRunInput 校验测试运行上传数据和测试类型，不写入数据库
+// 有多次尝试的测例会被推断出最终结果，导入历史数据的预检查（dry-run）也使用该函数
+func CheckTestRunInput(c *gin.Context, input *TestRunInput) error {
+	if err := validateTestRunInput(input); err != nil {
+		return err
+	}
+	return ValidateTestType(c, input.TestType, input.ProjectID)
+}
+
+// validateTestRunInput 校验测试运行上传数据
+func validateTestRunInput(input *TestRunInput) error {
+	// 验证 commit_id 最少8位
+	if len(input.CommitID) < 8 {
+		return fmt.Errorf("%w: commit_id must be at least 8 characters", ErrInvalidTestRunUpload)
+	}
+	if len(input.IdempotencyKey) > MaxIdempotencyKeyLength {
+		return fmt.Errorf("%w: idempotency key exceeds maximum length of %d characters", ErrInvalidTestRunUpload, MaxIdempotencyKeyLength)
+	}
+	if input.Status != "" && !models.TestRunStatus(input.Status).IsValid() {
+		return fmt.Errorf("%w: invalid status '%s'", ErrInvalidTestRunUpload, input.Status)
+	}
+	if input.CompletedAt != nil && (input.StartedAt == nil || input.CompletedAt.Before(*input.StartedAt)) {
+		return fmt.Errorf("%w: completed_at must not be earlier than started_at", ErrInvalidTestRunUpload)
+	}
+	if err := validateMetadata(input.Metadata); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTestRunUpload, err)
+	}
+	if err := validateCommitInput(input.Commit); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTestRunUpload, err)
+	}
+	if input.BaseCommitID != "" && !commitSHARe.MatchString(input.BaseCommitID) {
+		return fmt.Errorf("%w: invalid base_commit_id '%s'", ErrInvalidTestRunUpload, input.BaseCommitID)
+	}
+	return validateTestCaseInputs(input.TestCases)
+}
+
+// validateTestCaseInputs 校验测例名称和状态，有多次尝试时推断最终结果
+func validateTestCaseInputs(testCases []TestCaseInput) error {
+	for i := range testCases {
+		tc := &testCases[i]
+		if tc.Name == "" {
+			return fmt.Errorf("%w: test case name is required", ErrInvalidTestRunUpload)
+		}
+		if err := applyAttempts(tc); err != nil {
+			return err
+		}
+		if !tc.Status.IsValid() {
+			return fmt.Errorf("%w: invalid status '%s' for test case %s", ErrInvalidTestRunUpload, tc.Status, tc.Name)
+		}
+	}
+	return nil
+}
+
+// testCaseCounts 按状态统计的测例数量，流式上传时无需保留全部测例即可推断最终状态
+type testCaseCounts struct {
+	total   int
+	failed  int
+	skipped int
+}
+
+func (tc *testCaseCounts) add(status models.TestCaseStatus) {
+	tc.total++
+	switch status {
+	case models.TestCaseStatusFailed:
+		tc.failed++
+	case models.TestCaseStatusSkipped:
+		tc.skipped++
+	}
+}
+
+// derive 有任何失败测例为 failed；全部测例都被跳过时使用项目配置的 skippedOnlyStatus；否则为 passed
+func (tc *testCaseCounts) derive(skippedOnlyStatus models.TestRunStatus) models.TestRunStatus {
+	if tc.failed > 0 {
+		return models.TestRunStatusFailed
+	}
+	if tc.total > 0 && tc.skipped == tc.total {
+		return skippedOnlyStatus
+	}
+	return models.TestRunStatusPassed
+}
+
+// shortCommitID 截取 commit_short_id（前10位）
+func shortCommitID(commitID string) string {
+	if len(commitID) > 10 {
+		return commitID[:10]
+	}
+	return commitID
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+)
+
+// junitSuite JUnit中的testsuites/testsuite节点
+// 两者结构相同，根节点可以是任意一种，testsuite也允许嵌套
+type junitSuite struct {
+	XMLName   xml.Name        `xml:""`
+	Name      string          `xml:"name,attr"`
+	Suites    []junitSuite    `xml:"testsuite"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase JUnit中的testcase节点
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Time      string         `xml:"time,attr"`
+	Status    string         `xml:"status,attr"` // gtest扩展属性：run / notrun
+	Result    string         `xml:"result,attr"` // gtest扩展属性：completed / skipped / suppressed
+	Failures  []junitMessage `xml:"failure"`
+	Errors    []junitMessage `xml:"error"`
+	Skipped   *junitMessage  `xml:"skipped"`
+	SystemOut string         `xml:"system-out"`
+	SystemErr string         `xml:"system-err"`
+}
+
+// junitMessage failure/error/skipped节点
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ParseJUnitReport 解析JUnit XML报告，转换为测例上传数据
+// 失败重试产生的同名测例合并为多次尝试
+func ParseJUnitReport(r io.Reader) ([]TestCaseInput, error) {
+	var root junitSuite
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJUnitReport, err)
+	}
+	if root.XMLName.Local != "testsuites" && root.XMLName.Local != "testsuite" {
+		return nil, fmt.Errorf("%w: unexpected root element <%s>", ErrInvalidJUnitReport, root.XMLName.Local)
+	}
+
+	var testCases []TestCaseInput
+	if err := collectJUnitCases(&root, &testCases); err != nil {
+		return nil, err
+	}
+	return mergeRetriedCases(testCases), nil
+}
+
+// collectJUnitCases 递归收集testsuite下的所有测例
+func collectJUnitCases(suite *junitSuite, out *[]TestCaseInput) error {
+	for i := range suite.TestCases {
+		tc, err := convertJUnitCase(&suite.TestCases[i])
+		if err != nil {
+			return err
+		}
+		*out = append(*out, tc)
+	}
+	for i := range suite.Suites {
+		if err := collectJUnitCases(&suite.Suites[i], out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertJUnitCase 将单个testcase节点转换为测例上传数据
+func convertJUnitCase(jc *junitTestCase) (TestCaseInput, error) {
+	if jc.Name == "" {
+		return TestCaseInput{}, fmt.Errorf("%w: testcase without name", ErrInvalidJUnitReport)
+	}
+
+	// 测例名称使用 classname.name，与gtest的 Suite.Test 命名保持一致
+	name := jc.Name
+	if jc.ClassName != "" {
+		name = jc.ClassName + "." + jc.Name
+	}
+
+	tc := TestCaseInput{
+		Name:   name,
+		Status: models.TestCaseStatusPassed,
+	}
+
+	if jc.Time != "" {
+		seconds, err := strconv.ParseFloat(jc.Time, 64)
+		if err != nil {
+			return TestCaseInput{}, fmt.Errorf("%w: invalid time %q for testcase %s", ErrInvalidJUnitReport, jc.Time, name)
+		}
+		if seconds > 0 {
+			tc.DurationMs = uint32(seconds*1000 + 0.5)
+		}
+	}
+
+	// failure 和 error 都视为失败
+	if len(jc.Failures) > 0 || len(jc.Errors) > 0 {
+		tc.Status = models.TestCaseStatusFailed
+		var parts []string
+		for _, m := range append(jc.Failures, jc.Errors...) {
+			parts = append(parts, m.String())
+		}
+		tc.ErrorLog = strings.Join(parts, "\n")
+	} else if jc.Skipped != nil || jc.Status == "notrun" || jc.Result == "skipped" {
+		tc.Status = models.TestCaseStatusSkipped
+	}
+
+	var debug []string
+	if out := strings.TrimSpace(jc.SystemOut); out != "" {
+		debug = append(debug, out)
+	}
+	if errOut := strings.TrimSpace(jc.SystemErr); errOut != "" {
+		debug = append(debug, errOut)
+	}
+	tc.DebugLog = strings.Join(debug, "\n")
+
+	return tc, nil
+}
+
+// String 合并message属性和节点文本
+func (m junitMessage) String() string {
+	text := strings.TrimSpace(m.Text)
+	switch {
+	case m.Message == "":
+		return text
+	case text == "":
+		return m.Message
+	case strings.Contains(text, m.Message):
+		return text
+	default:
+		return m.Message + "\n" + text
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+)
+
+// gvisorJUnitReport gVisor 系统调用测试通过 --gtest_output=xml 生成的报告（节选）
+const gvisorJUnitReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="5" failures="1" disabled="0" errors="0" time="1.234" timestamp="2024-01-15T10:00:00" name="AllTests">
+  <testsuite name="ReadTest" tests="2" failures="1" disabled="0" skipped="0" errors="0" time="0.020" timestamp="2024-01-15T10:00:00">
+    <testcase name="ReadEmptyFile" status="run" result="completed" time="0.012" timestamp="2024-01-15T10:00:00" classname="ReadTest" />
+    <testcase name="ReadWithOffset" status="run" result="completed" time="0.008" timestamp="2024-01-15T10:00:00" classname="ReadTest">
+      <failure message="test/syscalls/linux/read.cc:52&#x0A;Value of: read(fd, buf, 1)&#x0A;Expected: 1&#x0A;  Actual: -1" type=""><![CDATA[test/syscalls/linux/read.cc:52
+Value of: read(fd, buf, 1)
+Expected: 1
+  Actual: -1]]></failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="SocketInetLoopbackTest/SocketInetReusePortTest" tests="1" failures="0" disabled="0" skipped="0" errors="0" time="0.500">
+    <testcase name="TcpPortReuseMultiThread/0" value_param="TestParam{V4Any, V4Loopback}" status="run" result="completed" time="0.5" classname="SocketInetLoopbackTest/SocketInetReusePortTest" />
+  </testsuite>
+  <testsuite name="EpollTest" tests="2" failures="0" disabled="1" skipped="1" errors="0" time="0">
+    <testcase name="DISABLED_Timeout" status="notrun" result="suppressed" time="0" classname="EpollTest" />
+    <testcase name="EdgeTriggered" status="run" result="skipped" time="0" classname="EpollTest">
+      <skipped message="test/syscalls/linux/epoll.cc:88&#x0A;Not supported on this platform" />
+    </testcase>
+  </testsuite>
+</testsuites>`
+
+func TestParseJUnitReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		report string
+		want   []TestCaseInput
+	}{
+		{
+			name:   "gvisor gtest report",
+			report: gvisorJUnitReport,
+			want: []TestCaseInput{
+				{Name: "ReadTest.ReadEmptyFile", Status: models.TestCaseStatusPassed, DurationMs: 12},
+				{
+					Name:       "ReadTest.ReadWithOffset",
+					Status:     models.TestCaseStatusFailed,
+					DurationMs: 8,
+					ErrorLog:   "test/syscalls/linux/read.cc:52\nValue of: read(fd, buf, 1)\nExpected: 1\n  Actual: -1",
+				},
+				{Name: "SocketInetLoopbackTest/SocketInetReusePortTest.TcpPortReuseMultiThread/0", Status: models.TestCaseStatusPassed, DurationMs: 500},
+				{Name: "EpollTest.DISABLED_Timeout", Status: models.TestCaseStatusSkipped},
+				{Name: "EpollTest.EdgeTriggered", Status: models.TestCaseStatusSkipped},
+			},
+		},
+		{
+			name: "nested suites with error, skipped and output",
+			report: `<testsuite name="root">
+  <testcase name="top" classname="pkg.Root" time="1.5" />
+  <testsuite name="inner">
+    <testcase name="crash" classname="pkg.Inner">
+      <error message="SIGSEGV">core dumped</error>
+      <system-out>  stdout line  </system-out>
+      <system-err>stderr line</system-err>
+    </testcase>
+    <testcase name="todo" classname="pkg.Inner"><skipped/></testcase>
+  </testsuite>
+</testsuite>`,
+			want: []TestCaseInput{
+				{Name: "pkg.Root.top", Status: models.TestCaseStatusPassed, DurationMs: 1500},
+				{Name: "pkg.Inner.crash", Status: models.TestCaseStatusFailed, ErrorLog: "SIGSEGV\ncore dumped", DebugLog: "stdout line\nstderr line"},
+				{Name: "pkg.Inner.todo", Status: models.TestCaseStatusSkipped},
+			},
+		},
+		{
+			name: "retried test case is merged into attempts",
+			report: `<testsuites>
+  <testsuite name="PipeTest">
+    <testcase name="Flaky" classname="PipeTest" time="0.1"><failure message="timeout"/></testcase>
+    <testcase name="Stable" classname="PipeTest" time="0.2" />
+    <testcase name="Flaky" classname="PipeTest" time="0.3" />
+  </testsuite>
+</testsuites>`,
+			want: []TestCaseInput{
+				{Name: "PipeTest.Flaky", Attempts: []TestCaseAttemptInput{
+					{Status: models.TestCaseStatusFailed, DurationMs: 100, ErrorLog: "timeout"},
+					{Status: models.TestCaseStatusPassed, DurationMs: 300},
+				}},
+				{Name: "PipeTest.Stable", Status: models.TestCaseStatusPassed, DurationMs: 200},
+			},
+		},
+		{
+			name:   "test case without classname",
+			report: `<testsuite><testcase name="standalone" time="0"/></testsuite>`,
+			want:   []TestCaseInput{{Name: "standalone", Status: models.TestCaseStatusPassed}},
+		},
+		{
+			name:   "empty report",
+			report: `<testsuites name="AllTests"></testsuites>`,
+			want:   []TestCaseInput{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJUnitReport(strings.NewReader(tt.report))
+			if err != nil {
+				t.Fatalf("ParseJUnitReport() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseJUnitReport() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJUnitReportInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		report string
+	}{
+		{name: "truncated report", report: gvisorJUnitReport[:len(gvisorJUnitReport)/2]},
+		{name: "empty input", report: ""},
+		{name: "unexpected root element", report: `<report><testcase name="a"/></report>`},
+		{name: "test case without name", report: `<testsuite><testcase classname="A"/></testsuite>`},
+		{name: "invalid time", report: `<testsuite><testcase name="a" time="fast"/></testsuite>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseJUnitReport(strings.NewReader(tt.report))
+			if !errors.Is(err, ErrInvalidJUnitReport) {
+				t.Errorf("ParseJUnitReport() error = %v, want %v", err, ErrInvalidJUnitReport)
+			}
+		})
+	}
+}
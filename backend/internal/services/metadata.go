@@ -0,0 +1,54 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+)
+
+// 测试运行元数据限制
+const (
+	MaxMetadataEntries     = 32
+	MaxMetadataKeyLength   = 64
+	MaxMetadataValueLength = 255
+)
+
+// metadataKeyRe 元数据键只允许字母、数字、下划线、点和短横线
+var metadataKeyRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// validateMetadata 校验元数据的数量和键值长度
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxMetadataEntries {
+		return fmt.Errorf("too many metadata entries (maximum %d)", MaxMetadataEntries)
+	}
+	for key, value := range metadata {
+		if len(key) > MaxMetadataKeyLength || !metadataKeyRe.MatchString(key) {
+			return fmt.Errorf("invalid metadata key '%s'", key)
+		}
+		if len(value) > MaxMetadataValueLength {
+			return fmt.Errorf("value of metadata key '%s' exceeds maximum length of %d characters", key, MaxMetadataValueLength)
+		}
+	}
+	return nil
+}
+
+// buildTestRunMetadata 将元数据转换为元数据模型，按键排序
+func buildTestRunMetadata(testRunID uint64, metadata map[string]string) []models.TestRunMetadata {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]models.TestRunMetadata, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, models.TestRunMetadata{
+			TestRunID: testRunID,
+			Key:       key,
+			Value:     metadata[key],
+		})
+	}
+	return entries
+}
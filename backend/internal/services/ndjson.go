@@ -0,0 +1,301 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NDJSON 流中的行类型
+const (
+	ndjsonLineRun  = "run"  // 第一行：运行信息
+	ndjsonLineCase = "case" // 每个测例一行
+	ndjsonLineEnd  = "end"  // 最后一行：最终状态
+)
+
+const (
+	// ndjsonMaxLineLength 单行的最大长度（单个测例的日志可能很长）
+	ndjsonMaxLineLength = 16 * 1024 * 1024
+	// ndjsonBatchSize 每批写入的测例数量，内存中最多保留一批测例
+	ndjsonBatchSize = 100
+)
+
+// ndjsonLine NDJSON 流中的一行，type 决定使用哪些字段
+type ndjsonLine struct {
+	Type string `json:"type"`
+
+	// run
+	ProjectID  *uint64           `json:"project_id"`
+	BranchName string            `json:"branch_name"`
+	CommitID   string            `json:"commit_id"`
+	TestType   string            `json:"test_type"`
+	Metadata   map[string]string `json:"metadata"`
+	Commit     *CommitInput      `json:"commit"`
+
+	PRNumber     *uint32 `json:"pr_number"`
+	BaseCommitID string  `json:"base_commit_id"`
+
+	// case，status 也用于 end
+	Name       string              `json:"name"`
+	Status     string              `json:"status"`
+	DurationMs uint32              `json:"duration_ms"`
+	ErrorLog   string              `json:"error_log"`
+	DebugLog   string              `json:"debug_log"`
+	Attempts   []ndjsonCaseAttempt `json:"attempts"`
+}
+
+// ndjsonCaseAttempt 测例行中的单次尝试
+type ndjsonCaseAttempt struct {
+	Status     string `json:"status"`
+	DurationMs uint32 `json:"duration_ms"`
+	ErrorLog   string `json:"error_log"`
+	DebugLog   string `json:"debug_log"`
+}
+
+// ndjsonStream 逐行读取 NDJSON 流，同时计算原始内容的哈希
+type ndjsonStream struct {
+	scanner *bufio.Scanner
+	tee     io.Reader
+	hasher  hash.Hash
+	lineNo  int
+}
+
+func newNDJSONStream(r io.Reader) *ndjsonStream {
+	hasher := sha256.New()
+	tee := io.TeeReader(r, hasher)
+	scanner := bufio.NewScanner(tee)
+	scanner.Buffer(make([]byte, 64*1024), ndjsonMaxLineLength)
+	return &ndjsonStream{scanner: scanner, tee: tee, hasher: hasher}
+}
+
+// next 读取下一个非空行，流结束时返回 io.EOF
+func (s *ndjsonStream) next() (*ndjsonLine, error) {
+	for s.scanner.Scan() {
+		s.lineNo++
+		data := bytes.TrimSpace(s.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var line ndjsonLine
+		if err := json.Unmarshal(data, &line); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidTestRunUpload, s.lineNo, err)
+		}
+		return &line, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidTestRunUpload, s.lineNo+1, err)
+	}
+	return nil, io.EOF
+}
+
+// sum 读完剩余内容并返回整个流的哈希
+func (s *ndjsonStream) sum() (string, error) {
+	if _, err := io.Copy(io.Discard, s.tee); err != nil {
+		return "", fmt.Errorf("failed to read request body: %w", err)
+	}
+	return hex.EncodeToString(s.hasher.Sum(nil)), nil
+}
+
+// toTestCaseInput 将测例行转换为测例上传数据
+func (l *ndjsonLine) toTestCaseInput() TestCaseInput {
+	tc := TestCaseInput{
+		Name:       l.Name,
+		Status:     models.TestCaseStatus(l.Status),
+		DurationMs: l.DurationMs,
+		ErrorLog:   l.ErrorLog,
+		DebugLog:   l.DebugLog,
+	}
+	for _, a := range l.Attempts {
+		tc.Attempts = append(tc.Attempts, TestCaseAttemptInput{
+			Status:     models.TestCaseStatus(a.Status),
+			DurationMs: a.DurationMs,
+			ErrorLog:   a.ErrorLog,
+			DebugLog:   a.DebugLog,
+		})
+	}
+	return tc
+}
+
+// IngestTestRunStream 从 NDJSON 流中创建测试运行
+// 第一行为运行信息（type=run），之后每行一个测例（type=case），最后一行为最终状态（type=end）；
+// 测试运行先以运行中状态写入，测例边读边分批在各自的短事务中写入，内存中最多保留一批测例，
+// 读完最后一行后设置最终状态。流中断、格式错误或缺少最后一行时删除已写入的测试运行和日志文件。
+// 幂等键的内容哈希基于原始请求体
+func IngestTestRunStream(c *gin.Context, r io.Reader, idempotencyKey string) (testRun *models.TestRun, replayed bool, err error) {
+	stream := newNDJSONStream(r)
+
+	header, err := stream.next()
+	if err == io.EOF {
+		return nil, false, fmt.Errorf("%w: empty stream", ErrInvalidTestRunUpload)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if header.Type != ndjsonLineRun {
+		return nil, false, fmt.Errorf("%w: line %d: first line must have type '%s'", ErrInvalidTestRunUpload, stream.lineNo, ndjsonLineRun)
+	}
+	if header.BranchName == "" || header.CommitID == "" || header.TestType == "" {
+		return nil, false, fmt.Errorf("%w: branch_name, commit_id and test_type are required", ErrInvalidTestRunUpload)
+	}
+
+	projectID, err := ResolveUploadProject(c, header.ProjectID)
+	if err != nil {
+		return nil, false, err
+	}
+	input := TestRunInput{
+		ProjectID:      projectID,
+		BranchName:     header.BranchName,
+		CommitID:       header.CommitID,
+		TestType:       header.TestType,
+		Metadata:       header.Metadata,
+		Commit:         header.Commit,
+		PRNumber:       header.PRNumber,
+		BaseCommitID:   header.BaseCommitID,
+		IdempotencyKey: idempotencyKey,
+	}
+	if err := CheckTestRunInput(c, &input); err != nil {
+		return nil, false, err
+	}
+
+	// 幂等键已被使用时不再解析测例，读完请求体后比较哈希
+	db := getDB(c)
+	if idempotencyKey != "" {
+		var count int64
+		if err := db.Model(&models.TestRun{}).
+			Where("project_id = ? AND idempotency_key = ?", projectID, idempotencyKey).
+			Count(&count).Error; err != nil {
+			return nil, false, fmt.Errorf("failed to find test run by idempotency key: %w", err)
+		}
+		if count > 0 {
+			payloadHash, err := stream.sum()
+			if err != nil {
+				return nil, false, err
+			}
+			existing, err := FindIdempotentTestRun(c, projectID, idempotencyKey, payloadHash)
+			return existing, existing != nil, err
+		}
+	}
+
+	testRun = input.newTestRun()
+	if idempotencyKey != "" {
+		testRun.IdempotencyKey = &idempotencyKey
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return createTestRun(tx, testRun, &input)
+	}); err != nil {
+		// 并发重试时另一个请求可能已经用相同幂等键创建了测试运行
+		if idempotencyKey != "" {
+			if payloadHash, sumErr := stream.sum(); sumErr == nil {
+				return recoverIdempotentIngest(c, projectID, idempotencyKey, payloadHash, err)
+			}
+		}
+		return nil, false, err
+	}
+
+	var blobPaths []string
+	counts, err := ingestNDJSONStream(c, stream, testRun, &blobPaths)
+	if err != nil {
+		if discardErr := discardTestRun(db, testRun.ID, blobPaths); discardErr != nil {
+			logger.LogError(c, logger.ModuleService, discardErr, "ingest_test_run_stream discard failed test_run_id=%d", testRun.ID)
+		}
+		return nil, false, err
+	}
+
+	logger.LogInfo(c, logger.ModuleService, "ingest_test_run_stream completed test_run_id=%d test_cases_count=%d status=%s",
+		testRun.ID, counts.total, testRun.Status)
+
+	return testRun, false, nil
+}
+
+// ingestNDJSONStream 读取测试运行之后的测例行和最后一行，每批测例在一个短事务中写入，
+// 读完后在一个事务中设置内容哈希和最终状态；已写入的日志文件路径追加到 blobPaths
+func ingestNDJSONStream(c *gin.Context, stream *ndjsonStream, testRun *models.TestRun, blobPaths *[]string) (testCaseCounts, error) {
+	var counts testCaseCounts
+	flush := func(batch []TestCaseInput) error {
+		paths, err := appendTestCaseBatch(c, testRun.ID, batch)
+		*blobPaths = append(*blobPaths, paths...)
+		return err
+	}
+
+	var trailer *ndjsonLine
+	batch := make([]TestCaseInput, 0, ndjsonBatchSize)
+	for trailer == nil {
+		line, err := stream.next()
+		if err == io.EOF {
+			return counts, fmt.Errorf("%w: stream ended without a line of type '%s'", ErrInvalidTestRunUpload, ndjsonLineEnd)
+		}
+		if err != nil {
+			return counts, err
+		}
+
+		switch line.Type {
+		case ndjsonLineCase:
+			batch = append(batch, line.toTestCaseInput())
+			if err := validateTestCaseInputs(batch[len(batch)-1:]); err != nil {
+				return counts, fmt.Errorf("line %d: %w", stream.lineNo, err)
+			}
+			counts.add(batch[len(batch)-1].Status)
+			if len(batch) == ndjsonBatchSize {
+				if err := flush(batch); err != nil {
+					return counts, err
+				}
+				batch = batch[:0]
+			}
+		case ndjsonLineEnd:
+			trailer = line
+		default:
+			return counts, fmt.Errorf("%w: line %d: unexpected type '%s'", ErrInvalidTestRunUpload, stream.lineNo, line.Type)
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(batch); err != nil {
+			return counts, err
+		}
+	}
+
+	// 最后一行之后不允许有其他内容
+	if _, err := stream.next(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("%w: line %d: unexpected content after the line of type '%s'", ErrInvalidTestRunUpload, stream.lineNo, ndjsonLineEnd)
+		}
+		return counts, err
+	}
+	status := models.TestRunStatus(trailer.Status)
+	if status != "" && !status.IsValid() {
+		return counts, fmt.Errorf("%w: invalid status '%s'", ErrInvalidTestRunUpload, trailer.Status)
+	}
+	payloadHash, err := stream.sum()
+	if err != nil {
+		return counts, err
+	}
+
+	db := getDB(c)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockTestRun(tx, testRun.ID)
+		if err != nil {
+			return err
+		}
+		if locked.IdempotencyKey != nil {
+			locked.PayloadHash = payloadHash
+			if err := tx.Model(locked).Update("payload_hash", payloadHash).Error; err != nil {
+				return fmt.Errorf("failed to update payload hash: %w", err)
+			}
+		}
+		if err := finalizeTestRun(tx, locked, status, counts, nil); err != nil {
+			return err
+		}
+		*testRun = *locked
+		return nil
+	})
+	return counts, err
+}
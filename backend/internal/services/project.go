@@ -9,6 +9,55 @@ import (
 	"gorm.io/gorm"
 )
 
+// DefaultProjectID 未绑定项目的API Key且未指定项目时使用的默认项目（DragonOS）
+const DefaultProjectID uint64 = 1
+
+// apiKeyProjectID 获取当前请求的API Key绑定的项目ID
+func apiKeyProjectID(c *gin.Context) (uint64, bool) {
+	if c == nil {
+		return 0, false
+	}
+	value, exists := c.Get("project_id")
+	if !exists {
+		return 0, false
+	}
+	projectID, ok := value.(uint64)
+	return projectID, ok
+}
+
+// ResolveUploadProject 确定上传数据所属的项目
+// 绑定项目的API Key只能上传到绑定的项目；未绑定的API Key可以指定项目，不指定时使用默认项目
+func ResolveUploadProject(c *gin.Context, requestedProjectID *uint64) (uint64, error) {
+	if boundProjectID, ok := apiKeyProjectID(c); ok {
+		if requestedProjectID != nil && *requestedProjectID != boundProjectID {
+			return 0, fmt.Errorf("%w: %d", ErrProjectForbidden, *requestedProjectID)
+		}
+		return boundProjectID, nil
+	}
+
+	if requestedProjectID == nil {
+		return DefaultProjectID, nil
+	}
+
+	db := getDB(c)
+	var project models.Project
+	if err := db.Select("id").First(&project, *requestedProjectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("%w: %d", ErrProjectNotFound, *requestedProjectID)
+		}
+		return 0, fmt.Errorf("failed to get project: %w", err)
+	}
+	return project.ID, nil
+}
+
+// CheckProjectAccess 检查当前请求的API Key是否可以访问该项目的数据
+func CheckProjectAccess(c *gin.Context, projectID uint64) error {
+	if boundProjectID, ok := apiKeyProjectID(c); ok && boundProjectID != projectID {
+		return fmt.Errorf("%w: %d", ErrProjectForbidden, projectID)
+	}
+	return nil
+}
+
 // ListProjects 列出所有项目
 func ListProjects(c *gin.Context) ([]models.Project, error) {
 	var projects []models.Project
@@ -29,8 +78,63 @@ func GetProjectByID(c *gin.Context, id uint64) (*models.Project, error) {
 	return &project, nil
 }
 
+// ProjectSettings 项目级配置，字段为空时保持原值（创建时使用默认值）
+type ProjectSettings struct {
+	LogInlineLimit    *uint32  `json:"log_inline_limit" binding:"omitempty,min=256,max=60000"`
+	SkippedOnlyStatus *string  `json:"skipped_only_status" binding:"omitempty,oneof=passed failed"`
+	RunTimeoutMinutes *uint32  `json:"run_timeout_minutes" binding:"omitempty,min=5,max=10080"`
+	FlakyWindowRuns   *uint32  `json:"flaky_window_runs" binding:"omitempty,min=5,max=500"`
+	SlowdownFactor    *float64 `json:"slowdown_factor" binding:"omitempty,gt=1,max=100"`
+}
+
+// apply 将配置写入项目
+func (s ProjectSettings) apply(project *models.Project) {
+	if s.LogInlineLimit != nil {
+		project.LogInlineLimit = *s.LogInlineLimit
+	}
+	if s.SkippedOnlyStatus != nil {
+		project.SkippedOnlyStatus = models.TestRunStatus(*s.SkippedOnlyStatus)
+	}
+	if s.RunTimeoutMinutes != nil {
+		project.RunTimeoutMinutes = *s.RunTimeoutMinutes
+	}
+	if s.FlakyWindowRuns != nil {
+		project.FlakyWindowRuns = *s.FlakyWindowRuns
+	}
+	if s.SlowdownFactor != nil {
+		project.SlowdownFactor = *s.SlowdownFactor
+	}
+}
+
+// loadProjectSettings 在事务中获取项目配置，未设置的配置使用默认值
+func loadProjectSettings(tx *gorm.DB, projectID uint64) (*models.Project, error) {
+	var project models.Project
+	if err := tx.First(&project, projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %d", ErrProjectNotFound, projectID)
+		}
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	if project.LogInlineLimit == 0 {
+		project.LogInlineLimit = models.DefaultLogInlineLimit
+	}
+	if project.SkippedOnlyStatus == "" {
+		project.SkippedOnlyStatus = models.TestRunStatusPassed
+	}
+	if project.RunTimeoutMinutes == 0 {
+		project.RunTimeoutMinutes = models.DefaultRunTimeoutMinutes
+	}
+	if project.FlakyWindowRuns == 0 {
+		project.FlakyWindowRuns = models.DefaultFlakyWindowRuns
+	}
+	if project.SlowdownFactor == 0 {
+		project.SlowdownFactor = models.DefaultSlowdownFactor
+	}
+	return &project, nil
+}
+
 // CreateProject 创建项目
-func CreateProject(c *gin.Context, name string, description string) (*models.Project, error) {
+func CreateProject(c *gin.Context, name string, description string, settings ProjectSettings) (*models.Project, error) {
 	db := getDB(c)
 
 	// 检查项目名称是否已存在
@@ -45,6 +149,7 @@ func CreateProject(c *gin.Context, name string, description string) (*models.Pro
 		Name:        name,
 		Description: description,
 	}
+	settings.apply(project)
 
 	if err := db.Create(project).Error; err != nil {
 		return nil, fmt.Errorf("failed to create project: %w", err)
@@ -54,7 +159,7 @@ func CreateProject(c *gin.Context, name string, description string) (*models.Pro
 }
 
 // UpdateProject 更新项目
-func UpdateProject(c *gin.Context, id uint64, name string, description string) (*models.Project, error) {
+func UpdateProject(c *gin.Context, id uint64, name string, description string, settings ProjectSettings) (*models.Project, error) {
 	db := getDB(c)
 
 	var project models.Project
@@ -75,11 +180,22 @@ func UpdateProject(c *gin.Context, id uint64, name string, description string) (
 		}
 	}
 
+	flakyWindowRuns := project.FlakyWindowRuns
 	project.Name = name
 	project.Description = description
+	settings.apply(&project)
 
-	if err := db.Save(&project).Error; err != nil {
-		return nil, fmt.Errorf("failed to update project: %w", err)
+	// 不稳定度窗口变化后重新计算项目下所有范围的不稳定度
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&project).Error; err != nil {
+			return fmt.Errorf("failed to update project: %w", err)
+		}
+		if project.FlakyWindowRuns != flakyWindowRuns {
+			return queueProjectAnalytics(tx, project.ID)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
 	return &project, nil
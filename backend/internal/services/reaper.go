@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/dragonos/dragonos-ci-dashboard/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// RunStaleRunReaper 定期取消超过项目超时时间没有活动的运行中测试，直到 ctx 结束
+// 用于 runner 异常退出后没有结束测试运行的情况
+func RunStaleRunReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reaped, err := ReapStaleTestRuns(time.Now())
+			if err != nil {
+				logger.LogError(nil, logger.ModuleService, err, "reap_stale_test_runs failed")
+				continue
+			}
+			if reaped > 0 {
+				logger.LogInfo(nil, logger.ModuleService, "reap_stale_test_runs cancelled=%d", reaped)
+			}
+		}
+	}
+}
+
+// ReapStaleTestRuns 取消所有超过项目超时时间没有活动的运行中测试，返回取消的数量
+func ReapStaleTestRuns(now time.Time) (int, error) {
+	db := getDB(nil)
+
+	var projects []models.Project
+	if err := db.Select("id", "run_timeout_minutes").Find(&projects).Error; err != nil {
+		return 0, fmt.Errorf("failed to get projects: %w", err)
+	}
+
+	reaped := 0
+	for _, project := range projects {
+		timeout := project.RunTimeoutMinutes
+		if timeout == 0 {
+			timeout = models.DefaultRunTimeoutMinutes
+		}
+		deadline := now.Add(-time.Duration(timeout) * time.Minute)
+
+		var ids []uint64
+		if err := db.Model(&models.TestRun{}).
+			Where("project_id = ? AND status = ? AND COALESCE(last_activity_at, started_at, created_at) < ?",
+				project.ID, models.TestRunStatusRunning, deadline).
+			Pluck("id", &ids).Error; err != nil {
+			return reaped, fmt.Errorf("failed to find stale test runs: %w", err)
+		}
+
+		reason := fmt.Sprintf("no activity for %d minutes, cancelled automatically", timeout)
+		for _, id := range ids {
+			cancelled, err := cancelStaleTestRun(db, id, deadline, reason)
+			if err != nil {
+				return reaped, err
+			}
+			if cancelled {
+				logger.LogWarn(nil, logger.ModuleService, "reap_stale_test_run test_run_id=%d project_id=%d timeout_minutes=%d",
+					id, project.ID, timeout)
+				reaped++
+			}
+		}
+	}
+
+	return reaped, nil
+}
+
+// cancelStaleTestRun 锁定并再次检查测试运行，仍然没有活动时取消
+// 查询和取消之间可能有新的心跳或测例上传，也可能被其他实例取消
+func cancelStaleTestRun(db *gorm.DB, id uint64, deadline time.Time, reason string) (bool, error) {
+	cancelled := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		testRun, err := lockTestRun(tx, id)
+		if err != nil {
+			return err
+		}
+		if testRun.Status != models.TestRunStatusRunning || !testRunLastActivity(testRun).Before(deadline) {
+			return nil
+		}
+
+		testRun.StatusReason = reason
+		if err := transitionTestRun(tx, testRun, models.TestRunStatusCancelled); err != nil {
+			return err
+		}
+		cancelled = true
+		return nil
+	})
+	return cancelled, err
+}
+
+// testRunLastActivity 测试运行最近一次活动的时间
+func testRunLastActivity(testRun *models.TestRun) time.Time {
+	switch {
+	case testRun.LastActivityAt != nil:
+		return *testRun.LastActivityAt
+	case testRun.StartedAt != nil:
+		return *testRun.StartedAt
+	default:
+		return testRun.CreatedAt
+	}
+}
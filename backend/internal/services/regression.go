@@ -0,0 +1,552 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// regressionLookbackRuns 查找最后一次通过时最多回溯的测试运行数量
+	regressionLookbackRuns = 500
+	// regressionChunkRuns 回溯时每批读取的测试运行数量
+	regressionChunkRuns = 50
+)
+
+// RegressionQueryParams 回归查询参数
+type RegressionQueryParams struct {
+	ProjectID *uint64
+	TestType  string
+	Name      string                  // 测例名称（模糊匹配）
+	Status    models.RegressionStatus // 为空时返回所有状态
+	Page      int
+	PageSize  int
+}
+
+// regressionObservation 测例在一次测试运行中的结果
+type regressionObservation struct {
+	TestRunID uint64
+	Name      string
+	Status    models.TestCaseStatus
+}
+
+// openRegression 未关闭的回归及其第一次失败的运行的位置
+type openRegression struct {
+	ID                uint64
+	Name              string
+	FirstBadRunID     *uint64
+	FirstBadCreatedAt *time.Time
+}
+
+// refreshRegressions 按创建时间顺序检测 master 上上次检测之后的每一次公开运行，由分析任务在不稳定度更新后调用
+//   - 先重新检查引用的运行被设为私有或删除的未关闭回归（见 recheckRegressions）
+//   - 有未关闭回归的测例在该运行中通过（包括重试后通过）时关闭回归
+//   - 没有未关闭回归的测例失败时，沿 master 历史向前查找连续失败的起点（第一次失败）和之前最后一次通过，
+//     找到最后一次通过时创建回归；从未通过的测例和被标记为不稳定的测例不会创建回归
+//
+// 遇到仍在运行的测试运行时停止，等它结束后再继续，保证按创建时间顺序检测；
+// 第一次检测时只检测最新的已完成运行，不回溯处理已有的历史
+func refreshRegressions(db *gorm.DB, projectID uint64, testType string) error {
+	var flakyNames []string
+	if err := db.Model(&models.FlakyTestCase{}).
+		Where("project_id = ? AND branch_name = ? AND test_type = ? AND is_flaky = ?",
+			projectID, models.MasterBranch, testType, true).
+		Pluck("name", &flakyNames).Error; err != nil {
+		return fmt.Errorf("failed to get flaky test cases: %w", err)
+	}
+	flaky := make(map[string]bool, len(flakyNames))
+	for _, name := range flakyNames {
+		flaky[name] = true
+	}
+
+	if err := recheckRegressions(db, projectID, testType); err != nil {
+		return err
+	}
+
+	cursor, err := getRegressionCursor(db, projectID, testType)
+	if err != nil {
+		return err
+	}
+	if cursor == nil {
+		var latest models.TestRun
+		if err := analyzedTestRuns(db, projectID, models.MasterBranch, testType).
+			Order("created_at DESC, id DESC").
+			Limit(1).
+			Find(&latest).Error; err != nil {
+			return fmt.Errorf("failed to get latest test run: %w", err)
+		}
+		if latest.ID == 0 {
+			return nil
+		}
+		if err := detectRegressions(db, &latest, flaky); err != nil {
+			return err
+		}
+		cursor = &models.RegressionCursor{LastRunID: latest.ID, LastRunCreatedAt: latest.CreatedAt}
+	}
+
+	for {
+		var runs []models.TestRun
+		if err := db.Where("project_id = ? AND branch_name = ? AND test_type = ? AND is_public = ? AND status IN ?",
+			projectID, models.MasterBranch, testType, true,
+			[]models.TestRunStatus{models.TestRunStatusRunning, models.TestRunStatusPassed, models.TestRunStatusFailed}).
+			Where("created_at > ? OR (created_at = ? AND id > ?)", cursor.LastRunCreatedAt, cursor.LastRunCreatedAt, cursor.LastRunID).
+			Order("created_at ASC, id ASC").
+			Limit(regressionChunkRuns).
+			Find(&runs).Error; err != nil {
+			return fmt.Errorf("failed to get test runs for regression detection: %w", err)
+		}
+
+		for i := range runs {
+			if runs[i].Status == models.TestRunStatusRunning {
+				return nil
+			}
+			if err := detectRegressions(db, &runs[i], flaky); err != nil {
+				return err
+			}
+		}
+		if len(runs) < regressionChunkRuns {
+			return nil
+		}
+		last := runs[len(runs)-1]
+		cursor = &models.RegressionCursor{LastRunID: last.ID, LastRunCreatedAt: last.CreatedAt}
+	}
+}
+
+// detectRegressions 根据一次 master 测试运行的结果打开或关闭回归，并将检测进度更新到该运行
+// 查找历史不在事务中进行，只有最后写入回归时在一个短事务中锁定项目并重新检查进度和未关闭的回归
+func detectRegressions(db *gorm.DB, testRun *models.TestRun, flaky map[string]bool) error {
+	projectID, testType := testRun.ProjectID, testRun.TestType
+
+	var results []regressionObservation
+	if err := db.Model(&models.TestCase{}).
+		Select("test_run_id, name, status").
+		Where("test_run_id = ? AND status <> ?", testRun.ID, models.TestCaseStatusSkipped).
+		Scan(&results).Error; err != nil {
+		return fmt.Errorf("failed to get test cases for regression detection: %w", err)
+	}
+
+	open, err := openRegressions(db, projectID, testType)
+	if err != nil {
+		return err
+	}
+
+	var fixedIDs []uint64
+	failing := make(map[string]bool)
+	for _, r := range results {
+		if o, ok := open[r.Name]; ok {
+			// 重新公开较早的运行后会从该运行重新检测，早于第一次失败的通过不能关闭回归
+			if r.Status == models.TestCaseStatusPassed &&
+				(o.FirstBadCreatedAt == nil || testRunAfter(testRun, *o.FirstBadCreatedAt, *o.FirstBadRunID)) {
+				fixedIDs = append(fixedIDs, o.ID)
+			}
+			continue
+		}
+		if r.Status == models.TestCaseStatusFailed && !flaky[r.Name] {
+			failing[r.Name] = true
+		}
+	}
+
+	var regressions []models.Regression
+	if len(failing) > 0 {
+		if regressions, err = findRegressionRanges(db, testRun, failing); err != nil {
+			return err
+		}
+	}
+
+	now := testRunCompletedAt(testRun)
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 锁定项目，避免多个服务实例同时写入同一项目的回归
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Project{}, projectID).Error; err != nil {
+			return fmt.Errorf("failed to lock project: %w", err)
+		}
+
+		// 查找历史期间其他实例可能已经检测过该运行
+		cursor, err := getRegressionCursor(tx, projectID, testType)
+		if err != nil {
+			return err
+		}
+		if cursor != nil && !testRunAfter(testRun, cursor.LastRunCreatedAt, cursor.LastRunID) {
+			return nil
+		}
+
+		if len(fixedIDs) > 0 {
+			if err := tx.Model(&models.Regression{}).
+				Where("id IN ? AND status = ?", fixedIDs, models.RegressionStatusOpen).
+				Updates(map[string]interface{}{
+					"status":          models.RegressionStatusClosed,
+					"fixed_run_id":    testRun.ID,
+					"fixed_commit_id": testRun.CommitID,
+					"closed_at":       now,
+					"updated_at":      now,
+				}).Error; err != nil {
+				return fmt.Errorf("failed to close regressions: %w", err)
+			}
+		}
+
+		if len(regressions) > 0 {
+			created, err := newRegressions(tx, projectID, testType, regressions)
+			if err != nil {
+				return err
+			}
+			for i := range created {
+				created[i].ProjectID = projectID
+				created[i].TestType = testType
+				created[i].Status = models.RegressionStatusOpen
+				created[i].UpdatedAt = now
+			}
+			if len(created) > 0 {
+				if err := tx.CreateInBatches(created, 500).Error; err != nil {
+					return fmt.Errorf("failed to save regressions: %w", err)
+				}
+			}
+		}
+
+		return saveRegressionCursor(tx, projectID, testType, testRun)
+	})
+}
+
+// newRegressions 过滤掉已有未关闭回归的测例和已经记录过的回归（同一测例和第一次失败的运行），
+// 重新检测较早的运行时不会重复创建回归
+func newRegressions(tx *gorm.DB, projectID uint64, testType string, regressions []models.Regression) ([]models.Regression, error) {
+	open, err := openRegressions(tx, projectID, testType)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(regressions))
+	firstBadIDs := make([]uint64, len(regressions))
+	for i, r := range regressions {
+		names[i] = r.Name
+		firstBadIDs[i] = *r.FirstBadRunID
+	}
+	var existing []models.Regression
+	if err := tx.Select("name, first_bad_run_id").
+		Where("project_id = ? AND test_type = ? AND name IN ? AND first_bad_run_id IN ?", projectID, testType, names, firstBadIDs).
+		Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to get existing regressions: %w", err)
+	}
+	type regressionKey struct {
+		name       string
+		firstBadID uint64
+	}
+	recorded := make(map[regressionKey]bool, len(existing))
+	for _, r := range existing {
+		recorded[regressionKey{r.Name, *r.FirstBadRunID}] = true
+	}
+
+	var created []models.Regression
+	for _, r := range regressions {
+		if _, ok := open[r.Name]; ok || recorded[regressionKey{r.Name, *r.FirstBadRunID}] {
+			continue
+		}
+		created = append(created, r)
+	}
+	return created, nil
+}
+
+// recheckRegressions 根据检测进度之前最近一次有该测例结果的公开 master 运行重新检查需要重新检查的未关闭回归：
+// 测例仍在失败时重新查找回归的提交范围，已经通过时关闭回归并记录该运行
+func recheckRegressions(db *gorm.DB, projectID uint64, testType string) error {
+	var pending []models.Regression
+	if err := db.Where("project_id = ? AND test_type = ? AND status = ? AND recheck = ?",
+		projectID, testType, models.RegressionStatusOpen, true).
+		Find(&pending).Error; err != nil {
+		return fmt.Errorf("failed to get regressions to recheck: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	cursor, err := getRegressionCursor(db, projectID, testType)
+	if err != nil {
+		return err
+	}
+
+	updates := make(map[uint64]map[string]interface{}, len(pending))
+	for _, regression := range pending {
+		query := db.Table("test_runs").
+			Joins("JOIN test_cases ON test_cases.test_run_id = test_runs.id").
+			Where("test_runs.project_id = ? AND test_runs.branch_name = ? AND test_runs.test_type = ? AND test_runs.is_public = ? AND test_runs.status IN ?",
+				projectID, models.MasterBranch, testType, true, []models.TestRunStatus{models.TestRunStatusPassed, models.TestRunStatusFailed}).
+			Where("test_cases.name = ? AND test_cases.status <> ?", regression.Name, models.TestCaseStatusSkipped)
+		if cursor != nil {
+			query = query.Where("test_runs.created_at < ? OR (test_runs.created_at = ? AND test_runs.id <= ?)",
+				cursor.LastRunCreatedAt, cursor.LastRunCreatedAt, cursor.LastRunID)
+		}
+		var latest struct {
+			models.TestRun
+			CaseStatus models.TestCaseStatus
+		}
+		if err := query.Select("test_runs.*, test_cases.status AS case_status").
+			Order("test_runs.created_at DESC, test_runs.id DESC").
+			Limit(1).
+			Scan(&latest).Error; err != nil {
+			return fmt.Errorf("failed to get latest result for regression %d: %w", regression.ID, err)
+		}
+
+		update := map[string]interface{}{"recheck": false}
+		switch {
+		case latest.ID == 0:
+		case latest.CaseStatus == models.TestCaseStatusPassed:
+			closedAt := testRunCompletedAt(&latest.TestRun)
+			update["status"] = models.RegressionStatusClosed
+			update["fixed_run_id"] = latest.ID
+			update["fixed_commit_id"] = latest.CommitID
+			update["closed_at"] = closedAt
+		default:
+			ranges, err := findRegressionRanges(db, &latest.TestRun, map[string]bool{regression.Name: true})
+			if err != nil {
+				return err
+			}
+			if len(ranges) > 0 {
+				update["last_good_run_id"] = ranges[0].LastGoodRunID
+				update["last_good_commit_id"] = ranges[0].LastGoodCommitID
+				update["first_bad_run_id"] = ranges[0].FirstBadRunID
+				update["first_bad_commit_id"] = ranges[0].FirstBadCommitID
+				update["opened_at"] = ranges[0].OpenedAt
+			}
+		}
+		update["updated_at"] = time.Now()
+		updates[regression.ID] = update
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Project{}, projectID).Error; err != nil {
+			return fmt.Errorf("failed to lock project: %w", err)
+		}
+		for id, update := range updates {
+			if err := tx.Model(&models.Regression{}).
+				Where("id = ? AND status = ? AND recheck = ?", id, models.RegressionStatusOpen, true).
+				Updates(update).Error; err != nil {
+				return fmt.Errorf("failed to recheck regression %d: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// openRegressions 返回项目和测试类型下未关闭回归的测例名称到回归的映射
+func openRegressions(db *gorm.DB, projectID uint64, testType string) (map[string]openRegression, error) {
+	var rows []openRegression
+	if err := db.Table("regressions").
+		Select("regressions.id, regressions.name, regressions.first_bad_run_id, test_runs.created_at AS first_bad_created_at").
+		Joins("LEFT JOIN test_runs ON test_runs.id = regressions.first_bad_run_id").
+		Where("regressions.project_id = ? AND regressions.test_type = ? AND regressions.status = ?",
+			projectID, testType, models.RegressionStatusOpen).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get open regressions: %w", err)
+	}
+	open := make(map[string]openRegression, len(rows))
+	for _, r := range rows {
+		open[r.Name] = r
+	}
+	return open, nil
+}
+
+// getRegressionCursor 获取项目和测试类型的回归检测进度，还没有检测过时返回 nil
+func getRegressionCursor(db *gorm.DB, projectID uint64, testType string) (*models.RegressionCursor, error) {
+	var cursors []models.RegressionCursor
+	if err := db.Where("project_id = ? AND test_type = ?", projectID, testType).
+		Limit(1).
+		Find(&cursors).Error; err != nil {
+		return nil, fmt.Errorf("failed to get regression cursor: %w", err)
+	}
+	if len(cursors) == 0 {
+		return nil, nil
+	}
+	return &cursors[0], nil
+}
+
+// saveRegressionCursor 将回归检测进度更新到 testRun
+func saveRegressionCursor(tx *gorm.DB, projectID uint64, testType string, testRun *models.TestRun) error {
+	cursor := models.RegressionCursor{
+		ProjectID:        projectID,
+		TestType:         testType,
+		LastRunID:        testRun.ID,
+		LastRunCreatedAt: testRun.CreatedAt,
+		UpdatedAt:        time.Now(),
+	}
+	if err := tx.Save(&cursor).Error; err != nil {
+		return fmt.Errorf("failed to save regression cursor: %w", err)
+	}
+	return nil
+}
+
+// testRunAfter 检查测试运行是否按（创建时间, ID）顺序排在给定位置之后
+func testRunAfter(testRun *models.TestRun, createdAt time.Time, id uint64) bool {
+	return testRun.CreatedAt.After(createdAt) || (testRun.CreatedAt.Equal(createdAt) && testRun.ID > id)
+}
+
+// invalidateRegressions 参与分析的 master 测试运行可见性变化或被删除前更新回归，之后由分析任务重新检测
+//   - 公开的运行被设为私有或删除时，回归中对该运行的引用置空（保留提交），引用该运行的未关闭回归标记为需要重新检查；
+//     已关闭的回归保持不变
+//   - 私有的运行重新公开时，检测进度回退到该运行之前，从该运行开始重新检测
+func invalidateRegressions(tx *gorm.DB, testRun *models.TestRun) error {
+	if testRun.BranchName != models.MasterBranch ||
+		(testRun.Status != models.TestRunStatusPassed && testRun.Status != models.TestRunStatusFailed) {
+		return nil
+	}
+
+	if !testRun.IsPublic {
+		cursor, err := getRegressionCursor(tx, testRun.ProjectID, testRun.TestType)
+		if err != nil || cursor == nil || testRunAfter(testRun, cursor.LastRunCreatedAt, cursor.LastRunID) {
+			return err
+		}
+		// 回退到该运行的创建时间，同一时间创建的运行会重新检测，不影响结果
+		if err := tx.Model(cursor).Updates(map[string]interface{}{
+			"last_run_id":         0,
+			"last_run_created_at": testRun.CreatedAt,
+			"updated_at":          time.Now(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to rewind regression cursor: %w", err)
+		}
+		return nil
+	}
+
+	scope := tx.Model(&models.Regression{}).Where("project_id = ? AND test_type = ?", testRun.ProjectID, testRun.TestType)
+	if err := scope.Session(&gorm.Session{}).
+		Where("status = ? AND (last_good_run_id = ? OR first_bad_run_id = ?)", models.RegressionStatusOpen, testRun.ID, testRun.ID).
+		Update("recheck", true).Error; err != nil {
+		return fmt.Errorf("failed to mark regressions for recheck: %w", err)
+	}
+	for _, column := range []string{"last_good_run_id", "first_bad_run_id", "fixed_run_id"} {
+		if err := scope.Session(&gorm.Session{}).
+			Where(column+" = ?", testRun.ID).
+			Update(column, nil).Error; err != nil {
+			return fmt.Errorf("failed to detach test run from regressions: %w", err)
+		}
+	}
+	return nil
+}
+
+// findRegressionRanges 从 testRun 开始沿 master 历史向前查找失败测例的第一次失败和最后一次通过
+// 最多回溯 regressionLookbackRuns 次测试运行，跳过的测例和没有该测例的运行不影响结果；
+// 回归的打开时间为第一次失败的运行的完成时间
+func findRegressionRanges(db *gorm.DB, testRun *models.TestRun, failing map[string]bool) ([]models.Regression, error) {
+	firstBad := make(map[string]models.TestRun, len(failing))
+	for name := range failing {
+		firstBad[name] = *testRun
+	}
+
+	var regressions []models.Regression
+	for offset := 0; offset < regressionLookbackRuns && len(firstBad) > 0; offset += regressionChunkRuns {
+		var runs []models.TestRun
+		if err := analyzedTestRuns(db, testRun.ProjectID, testRun.BranchName, testRun.TestType).
+			Select("id, commit_id, created_at, completed_at").
+			Where("created_at < ? OR (created_at = ? AND id < ?)", testRun.CreatedAt, testRun.CreatedAt, testRun.ID).
+			Order("created_at DESC, id DESC").
+			Offset(offset).
+			Limit(regressionChunkRuns).
+			Find(&runs).Error; err != nil {
+			return nil, fmt.Errorf("failed to get test runs for regression detection: %w", err)
+		}
+		if len(runs) == 0 {
+			break
+		}
+
+		runIDs := make([]uint64, len(runs))
+		runIndex := make(map[uint64]int, len(runs))
+		for i, run := range runs {
+			runIDs[i] = run.ID
+			runIndex[run.ID] = i
+		}
+		names := make([]string, 0, len(firstBad))
+		for name := range firstBad {
+			names = append(names, name)
+		}
+
+		var observations []regressionObservation
+		if err := db.Model(&models.TestCase{}).
+			Select("test_run_id, name, status").
+			Where("test_run_id IN ? AND name IN ? AND status <> ?", runIDs, names, models.TestCaseStatusSkipped).
+			Scan(&observations).Error; err != nil {
+			return nil, fmt.Errorf("failed to get test cases for regression detection: %w", err)
+		}
+		// 从新到旧
+		sort.SliceStable(observations, func(i, j int) bool {
+			return runIndex[observations[i].TestRunID] < runIndex[observations[j].TestRunID]
+		})
+
+		for _, o := range observations {
+			bad, pending := firstBad[o.Name]
+			if !pending {
+				continue
+			}
+			run := runs[runIndex[o.TestRunID]]
+			if o.Status == models.TestCaseStatusFailed {
+				firstBad[o.Name] = run
+				continue
+			}
+			lastGoodID, firstBadID := run.ID, bad.ID
+			regressions = append(regressions, models.Regression{
+				Name:             o.Name,
+				LastGoodRunID:    &lastGoodID,
+				LastGoodCommitID: run.CommitID,
+				FirstBadRunID:    &firstBadID,
+				FirstBadCommitID: bad.CommitID,
+				OpenedAt:         testRunCompletedAt(&bad),
+			})
+			delete(firstBad, o.Name)
+		}
+	}
+
+	sort.Slice(regressions, func(i, j int) bool {
+		return regressions[i].Name < regressions[j].Name
+	})
+	return regressions, nil
+}
+
+// testRunCompletedAt 测试运行的完成时间，没有完成时间时使用创建时间
+func testRunCompletedAt(testRun *models.TestRun) time.Time {
+	if testRun.CompletedAt != nil {
+		return *testRun.CompletedAt
+	}
+	return testRun.CreatedAt
+}
+
+// QueryRegressions 列出回归，最近打开的排在前面
+func QueryRegressions(c *gin.Context, params RegressionQueryParams) ([]models.Regression, int64, error) {
+	var regressions []models.Regression
+	var total int64
+
+	db := getDB(c)
+	query := db.Model(&models.Regression{})
+	if params.ProjectID != nil {
+		query = query.Where("project_id = ?", *params.ProjectID)
+	}
+	if params.TestType != "" {
+		query = query.Where("test_type = ?", params.TestType)
+	}
+	if params.Name != "" {
+		query = query.Where("name LIKE ?", "%"+params.Name+"%")
+	}
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count regressions: %w", err)
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	if err := query.Order("opened_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&regressions).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to query regressions: %w", err)
+	}
+	return regressions, total, nil
+}
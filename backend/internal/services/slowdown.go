@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// DurationBaselineRuns 计算测例耗时基线时使用的最近测试运行数量
+	DurationBaselineRuns = 20
+	// durationBaselineMinSamples 基线至少需要的历史耗时数量，样本太少时不判断是否变慢
+	durationBaselineMinSamples = 3
+	// durationBaselinePercentile 基线中除中位数外返回的百分位
+	durationBaselinePercentile = 0.9
+)
+
+// SlowdownParams 测例变慢查询参数
+type SlowdownParams struct {
+	Factor             float64 // 为0时使用项目配置的 slowdown_factor
+	MinDurationDeltaMs uint32
+}
+
+// durationBaseline 测例在最近测试运行中的耗时基线
+type durationBaseline struct {
+	Samples  int
+	MedianMs uint32
+	P90Ms    uint32
+}
+
+// TestCaseSlowdown 耗时超过基线的测例
+type TestCaseSlowdown struct {
+	TestCaseID       uint64  `json:"test_case_id"`
+	Name             string  `json:"name"`
+	DurationMs       uint32  `json:"duration_ms"`
+	BaselineMedianMs uint32  `json:"baseline_median_ms"`
+	BaselineP90Ms    uint32  `json:"baseline_p90_ms"`
+	BaselineSamples  int     `json:"baseline_samples"`
+	Ratio            float64 `json:"ratio"` // duration_ms / baseline_median_ms
+	DeltaMs          int64   `json:"delta_ms"`
+}
+
+// TestRunSlowdowns 测试运行中耗时超过基线的测例，按增加的耗时从大到小排列
+type TestRunSlowdowns struct {
+	TestRunID          uint64             `json:"test_run_id"`
+	BranchName         string             `json:"branch_name"`
+	TestType           string             `json:"test_type"`
+	Factor             float64            `json:"factor"`
+	MinDurationDeltaMs uint32             `json:"min_duration_delta_ms"`
+	BaselineRuns       int                `json:"baseline_runs"` // 参与计算基线的测试运行数量
+	Slowdowns          []TestCaseSlowdown `json:"slowdowns"`
+}
+
+// GetTestRunSlowdowns 找出测试运行中耗时超过基线的测例
+// 基线为同一项目、分支和测试类型中该运行之前最近 DurationBaselineRuns 次公开且已完成的运行里通过的测例耗时；
+// 只比较通过的测例，耗时达到中位数的 Factor 倍且增加至少 MinDurationDeltaMs 时视为变慢。
+// 倍数不合法时返回 ErrInvalidSlowdownParams
+func GetTestRunSlowdowns(c *gin.Context, testRun *models.TestRun, params SlowdownParams) (*TestRunSlowdowns, error) {
+	db := getDB(c)
+	if params.Factor == 0 {
+		project, err := loadProjectSettings(db, testRun.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		params.Factor = project.SlowdownFactor
+	}
+	if params.Factor <= 1 {
+		return nil, fmt.Errorf("%w: factor must be greater than 1", ErrInvalidSlowdownParams)
+	}
+
+	result := &TestRunSlowdowns{
+		TestRunID:          testRun.ID,
+		BranchName:         testRun.BranchName,
+		TestType:           testRun.TestType,
+		Factor:             params.Factor,
+		MinDurationDeltaMs: params.MinDurationDeltaMs,
+		Slowdowns:          []TestCaseSlowdown{},
+	}
+
+	var runIDs []uint64
+	if err := analyzedTestRuns(db, testRun.ProjectID, testRun.BranchName, testRun.TestType).
+		Where("created_at < ? OR (created_at = ? AND id < ?)", testRun.CreatedAt, testRun.CreatedAt, testRun.ID).
+		Order("created_at DESC, id DESC").
+		Limit(DurationBaselineRuns).
+		Pluck("id", &runIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get baseline test runs: %w", err)
+	}
+	result.BaselineRuns = len(runIDs)
+	if len(runIDs) == 0 {
+		return result, nil
+	}
+
+	baselines, err := loadDurationBaselines(db, runIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []models.TestCase
+	if err := db.Select("id, name, duration_ms").
+		Where("test_run_id = ? AND status = ?", testRun.ID, models.TestCaseStatusPassed).
+		Find(&cases).Error; err != nil {
+		return nil, fmt.Errorf("failed to get test cases: %w", err)
+	}
+
+	for _, tc := range cases {
+		baseline, ok := baselines[tc.Name]
+		if !ok || baseline.Samples < durationBaselineMinSamples {
+			continue
+		}
+		delta := int64(tc.DurationMs) - int64(baseline.MedianMs)
+		if delta <= 0 || delta < int64(params.MinDurationDeltaMs) ||
+			float64(tc.DurationMs) < float64(baseline.MedianMs)*params.Factor {
+			continue
+		}
+		slowdown := TestCaseSlowdown{
+			TestCaseID:       tc.ID,
+			Name:             tc.Name,
+			DurationMs:       tc.DurationMs,
+			BaselineMedianMs: baseline.MedianMs,
+			BaselineP90Ms:    baseline.P90Ms,
+			BaselineSamples:  baseline.Samples,
+			DeltaMs:          delta,
+		}
+		if baseline.MedianMs > 0 {
+			slowdown.Ratio = float64(tc.DurationMs) / float64(baseline.MedianMs)
+		}
+		result.Slowdowns = append(result.Slowdowns, slowdown)
+	}
+
+	sort.SliceStable(result.Slowdowns, func(i, j int) bool {
+		if result.Slowdowns[i].DeltaMs != result.Slowdowns[j].DeltaMs {
+			return result.Slowdowns[i].DeltaMs > result.Slowdowns[j].DeltaMs
+		}
+		return result.Slowdowns[i].Name < result.Slowdowns[j].Name
+	})
+	return result, nil
+}
+
+// loadDurationBaselines 按测例名称计算通过的测例在给定测试运行中的耗时中位数和百分位
+func loadDurationBaselines(db *gorm.DB, runIDs []uint64) (map[string]durationBaseline, error) {
+	var samples []caseSnapshot
+	if err := db.Model(&models.TestCase{}).
+		Select("name, duration_ms").
+		Where("test_run_id IN ? AND status = ?", runIDs, models.TestCaseStatusPassed).
+		Scan(&samples).Error; err != nil {
+		return nil, fmt.Errorf("failed to get baseline test cases: %w", err)
+	}
+
+	durations := make(map[string][]uint32)
+	for _, s := range samples {
+		durations[s.Name] = append(durations[s.Name], s.DurationMs)
+	}
+
+	baselines := make(map[string]durationBaseline, len(durations))
+	for name, values := range durations {
+		baselines[name] = computeDurationBaseline(values)
+	}
+	return baselines, nil
+}
+
+// computeDurationBaseline 计算耗时的中位数和 durationBaselinePercentile 百分位（最近秩法）
+func computeDurationBaseline(values []uint32) durationBaseline {
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	n := len(values)
+	baseline := durationBaseline{Samples: n}
+	if n == 0 {
+		return baseline
+	}
+	if n%2 == 1 {
+		baseline.MedianMs = values[n/2]
+	} else {
+		baseline.MedianMs = uint32((uint64(values[n/2-1]) + uint64(values[n/2])) / 2)
+	}
+	rank := int(math.Ceil(durationBaselinePercentile * float64(n)))
+	baseline.P90Ms = values[max(rank, 1)-1]
+	return baseline
+}
@@ -1,75 +1,362 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// CreateTestCase 创建测例
-func CreateTestCase(testRunID uint64, name string, status models.TestCaseStatus, durationMs uint32, errorLog, debugLog string) (*models.TestCase, error) {
-	testCase := &models.TestCase{
-		TestRunID:  testRunID,
-		Name:       name,
-		Status:     status,
-		DurationMs: durationMs,
-		ErrorLog:   errorLog,
-		DebugLog:   debugLog,
-	}
-
-	if err := models.DB.Create(testCase).Error; err != nil {
-		return nil, fmt.Errorf("failed to create test case: %w", err)
-	}
+// MaxTestCaseAttempts 单个测例最多的尝试次数
+const MaxTestCaseAttempts = 10
 
-	return testCase, nil
+// TestCaseInput 测例上传数据，各种上传格式解析后统一转换为该结构
+type TestCaseInput struct {
+	Name       string
+	Status     models.TestCaseStatus
+	DurationMs uint32
+	ErrorLog   string
+	DebugLog   string
+	Attempts   []TestCaseAttemptInput // 失败重试时的每次尝试，最终结果为最后一次尝试
 }
 
-// BatchCreateTestCases 批量创建测例
-func BatchCreateTestCases(c *gin.Context, testRunID uint64, testCases []struct {
-	Name       string
+// TestCaseAttemptInput 测例单次尝试的上传数据
+type TestCaseAttemptInput struct {
 	Status     models.TestCaseStatus
 	DurationMs uint32
 	ErrorLog   string
 	DebugLog   string
-}) error {
-	if len(testCases) == 0 {
+}
+
+// applyAttempts 根据多次尝试推断测例的最终结果
+// 最终状态为最后一次尝试的状态；未指定时耗时为所有尝试之和，
+// 错误日志取最后一次失败尝试的错误日志，调试日志取最后一次尝试的调试日志
+func applyAttempts(tc *TestCaseInput) error {
+	if len(tc.Attempts) == 0 {
 		return nil
 	}
+	if len(tc.Attempts) > MaxTestCaseAttempts {
+		return fmt.Errorf("%w: test case %s has more than %d attempts", ErrInvalidTestRunUpload, tc.Name, MaxTestCaseAttempts)
+	}
+	for i, a := range tc.Attempts {
+		if !a.Status.IsValid() {
+			return fmt.Errorf("%w: invalid status '%s' for attempt %d of test case %s", ErrInvalidTestRunUpload, a.Status, i+1, tc.Name)
+		}
+	}
 
-	cases := make([]models.TestCase, 0, len(testCases))
+	last := tc.Attempts[len(tc.Attempts)-1]
+	if tc.Status != "" && tc.Status != last.Status {
+		return fmt.Errorf("%w: status of test case %s does not match its last attempt", ErrInvalidTestRunUpload, tc.Name)
+	}
+	tc.Status = last.Status
+
+	if tc.DurationMs == 0 {
+		for _, a := range tc.Attempts {
+			tc.DurationMs += a.DurationMs
+		}
+	}
+	if tc.ErrorLog == "" {
+		for i := len(tc.Attempts) - 1; i >= 0; i-- {
+			if tc.Attempts[i].Status == models.TestCaseStatusFailed && tc.Attempts[i].ErrorLog != "" {
+				tc.ErrorLog = tc.Attempts[i].ErrorLog
+				break
+			}
+		}
+	}
+	if tc.DebugLog == "" {
+		tc.DebugLog = last.DebugLog
+	}
+	return nil
+}
+
+// passedOnRetry 检查测例是否在之前的尝试失败后重试通过
+func passedOnRetry(tc *TestCaseInput) bool {
+	if tc.Status != models.TestCaseStatusPassed {
+		return false
+	}
+	for _, a := range tc.Attempts {
+		if a.Status == models.TestCaseStatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeRetriedCases 将报告中重复出现的同名测例合并为一个测例的多次尝试
+// 测试运行器失败重试时会再次输出同名测例，合并后的测例位于第一次出现的位置
+func mergeRetriedCases(testCases []TestCaseInput) []TestCaseInput {
+	index := make(map[string]int, len(testCases))
+	merged := make([]TestCaseInput, 0, len(testCases))
 	for _, tc := range testCases {
-		cases = append(cases, models.TestCase{
-			TestRunID:  testRunID,
-			Name:       tc.Name,
+		attempt := TestCaseAttemptInput{
 			Status:     tc.Status,
 			DurationMs: tc.DurationMs,
 			ErrorLog:   tc.ErrorLog,
 			DebugLog:   tc.DebugLog,
+		}
+		i, seen := index[tc.Name]
+		if !seen {
+			index[tc.Name] = len(merged)
+			merged = append(merged, tc)
+			continue
+		}
+
+		first := &merged[i]
+		if len(first.Attempts) == 0 {
+			first.Attempts = []TestCaseAttemptInput{{
+				Status:     first.Status,
+				DurationMs: first.DurationMs,
+				ErrorLog:   first.ErrorLog,
+				DebugLog:   first.DebugLog,
+			}}
+			*first = TestCaseInput{Name: first.Name, Attempts: first.Attempts}
+		}
+		first.Attempts = append(first.Attempts, attempt)
+	}
+	return merged
+}
+
+// buildTestCases 将测例上传数据转换为测例模型，超过 logLimit 的日志只保留预览
+func buildTestCases(testRunID uint64, testCases []TestCaseInput, logLimit int) []models.TestCase {
+	cases := make([]models.TestCase, 0, len(testCases))
+	for _, tc := range testCases {
+		suite, testName, param := splitTestCaseName(tc.Name)
+		cases = append(cases, models.TestCase{
+			TestRunID:         testRunID,
+			Name:              tc.Name,
+			Suite:             suite,
+			TestName:          testName,
+			Param:             param,
+			Status:            tc.Status,
+			DurationMs:        tc.DurationMs,
+			ErrorLog:          truncateLog(tc.ErrorLog, logLimit),
+			DebugLog:          truncateLog(tc.DebugLog, logLimit),
+			ErrorLogTruncated: len(tc.ErrorLog) > logLimit,
+			DebugLogTruncated: len(tc.DebugLog) > logLimit,
+			AttemptCount:      uint16(max(len(tc.Attempts), 1)),
+			PassedOnRetry:     passedOnRetry(&tc),
 		})
 	}
+	return cases
+}
+
+// AppendTestCases 向运行中的测试运行追加一批测例
+// 测试运行已结束时返回 ErrTestRunNotRunning
+func AppendTestCases(c *gin.Context, testRunID uint64, testCases []TestCaseInput) error {
+	if err := validateTestCaseInputs(testCases); err != nil {
+		return err
+	}
+
+	blobPaths, err := appendTestCaseBatch(c, testRunID, testCases)
+	if err != nil {
+		removeFiles(blobPaths)
+	}
+	return err
+}
+
+// appendTestCaseBatch 在一个短事务中锁定运行中的测试运行，写入一批已校验的测例并记录活动时间
+// 返回已写入的日志文件路径，事务回滚时由调用方删除
+func appendTestCaseBatch(c *gin.Context, testRunID uint64, testCases []TestCaseInput) ([]string, error) {
+	var blobPaths []string
+	db := getDB(c)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		testRun, err := lockTestRun(tx, testRunID)
+		if err != nil {
+			return err
+		}
+		if err := CheckProjectAccess(c, testRun.ProjectID); err != nil {
+			return err
+		}
+		if testRun.Status != models.TestRunStatusRunning {
+			return ErrTestRunNotRunning
+		}
+
+		paths, err := insertTestCases(tx, testRun, testCases)
+		blobPaths = paths
+		if err != nil {
+			return err
+		}
+
+		testRun.Touch()
+		if err := tx.Model(testRun).Update("last_activity_at", testRun.LastActivityAt).Error; err != nil {
+			return fmt.Errorf("failed to update test run activity: %w", err)
+		}
+		return nil
+	})
+	return blobPaths, err
+}
+
+// splitTestCaseName 将测例名称拆分为 suite、test 和参数
+// gtest 名称格式为 [实例化前缀/]Suite.Test[/参数]，JUnit 名称格式为 classname.name[参数]
+// （如 test[1.5]，参数不含方括号）。参数中可能包含 '.'，因此只在第一个 '['
+// 和 suite 之后的第一个 '/' 之前查找最后一个 '.' 来分隔 suite 和 test
+func splitTestCaseName(name string) (suite, test, param string) {
+	end := len(name)
+	if bracket := strings.Index(name, "["); bracket >= 0 {
+		end = bracket
+	}
+	if firstDot := strings.Index(name[:end], "."); firstDot >= 0 {
+		if slash := strings.Index(name[firstDot:end], "/"); slash >= 0 {
+			end = firstDot + slash
+		}
+	}
+
+	start := 0
+	if dot := strings.LastIndex(name[:end], "."); dot >= 0 {
+		suite, start = name[:dot], dot+1
+	}
+	test = name[start:end]
+	if end < len(name) {
+		switch name[end] {
+		case '/':
+			param = name[end+1:]
+		case '[':
+			param = strings.TrimSuffix(name[end+1:], "]")
+		}
+	}
+	return suite, test, param
+}
+
+// BackfillTestCaseNames 按ID顺序分批重新拆分已有测例的名称，返回更新的测例数量
+// 拆分结果没有变化的测例不会更新，可以重复执行；
+// progress 不为空时每批处理完后调用
+func BackfillTestCaseNames(c *gin.Context, batchSize int, progress func(scanned, updated int)) (int, error) {
+	if batchSize < 1 {
+		batchSize = 500
+	}
 
 	db := getDB(c)
-	return db.CreateInBatches(cases, 100).Error
+	var lastID uint64
+	scanned, updated := 0, 0
+	for {
+		var testCases []models.TestCase
+		if err := db.Select("id, name, suite, test_name, param").
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&testCases).Error; err != nil {
+			return updated, fmt.Errorf("failed to get test cases: %w", err)
+		}
+		if len(testCases) == 0 {
+			return updated, nil
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, tc := range testCases {
+				suite, testName, param := splitTestCaseName(tc.Name)
+				if suite == tc.Suite && testName == tc.TestName && param == tc.Param {
+					continue
+				}
+				if err := tx.Model(&models.TestCase{}).Where("id = ?", tc.ID).UpdateColumns(map[string]interface{}{
+					"suite":     suite,
+					"test_name": testName,
+					"param":     param,
+				}).Error; err != nil {
+					return fmt.Errorf("failed to update test case %d: %w", tc.ID, err)
+				}
+				updated++
+			}
+			return nil
+		})
+		if err != nil {
+			return updated, err
+		}
+
+		scanned += len(testCases)
+		lastID = testCases[len(testCases)-1].ID
+		if progress != nil {
+			progress(scanned, updated)
+		}
+	}
 }
 
-// GetTestCasesByTestRunID 根据测试运行ID获取测例列表
-func GetTestCasesByTestRunID(c *gin.Context, testRunID uint64) ([]models.TestCase, error) {
+// GetTestCasesByTestRunID 根据测试运行ID获取测例列表，suite 不为空时只返回该 suite 的测例
+// 测例会根据所在项目、分支和测试类型的最近运行标记是否不稳定
+func GetTestCasesByTestRunID(c *gin.Context, testRunID uint64, suite string) ([]models.TestCase, error) {
 	var testCases []models.TestCase
 	db := getDB(c)
-	if err := db.Where("test_run_id = ?", testRunID).
-		Order("status DESC, name ASC").
+	query := db.Where("test_run_id = ?", testRunID)
+	if suite != "" {
+		query = query.Where("suite = ?", suite)
+	}
+	if err := query.
+		Preload("Attempts", func(db *gorm.DB) *gorm.DB {
+			return db.Order("attempt ASC")
+		}).
+		Preload("OutputFiles", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at DESC")
+		}).
+		Order("status DESC, suite ASC, test_name ASC, LENGTH(param) ASC, param ASC").
 		Find(&testCases).Error; err != nil {
 		return nil, err
 	}
+	if err := markFlakyTestCases(db, testRunID, testCases); err != nil {
+		return nil, err
+	}
 	return testCases, nil
 }
 
-// GetTestCaseByID 根据ID获取测例
-func GetTestCaseByID(id uint64) (*models.TestCase, error) {
+// SuiteRollup 测试运行中单个 suite 的测例统计
+type SuiteRollup struct {
+	Suite         string  `json:"suite"`
+	TotalCases    int64   `json:"total_cases"`
+	PassedCases   int64   `json:"passed_cases"`
+	FailedCases   int64   `json:"failed_cases"`
+	SkippedCases  int64   `json:"skipped_cases"`
+	PassedOnRetry int64   `json:"passed_on_retry_cases"`
+	PassRate      float64 `json:"pass_rate"`
+	Duration      int64   `json:"duration"` // 总耗时（毫秒）
+}
+
+// GetSuiteRollups 按 suite 汇总测试运行的测例状态，失败最多的 suite 排在前面
+func GetSuiteRollups(c *gin.Context, testRunID uint64) ([]SuiteRollup, error) {
+	var rollups []SuiteRollup
+	db := getDB(c)
+	if err := db.Model(&models.TestCase{}).
+		Select("suite, COUNT(*) AS total_cases, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS passed_cases, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS failed_cases, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS skipped_cases, "+
+			"SUM(CASE WHEN passed_on_retry THEN 1 ELSE 0 END) AS passed_on_retry, "+
+			"COALESCE(SUM(duration_ms), 0) AS duration",
+			models.TestCaseStatusPassed, models.TestCaseStatusFailed, models.TestCaseStatusSkipped).
+		Where("test_run_id = ?", testRunID).
+		Group("suite").
+		Order("failed_cases DESC, suite ASC").
+		Scan(&rollups).Error; err != nil {
+		return nil, fmt.Errorf("failed to get suite rollups: %w", err)
+	}
+
+	for i := range rollups {
+		if rollups[i].TotalCases > 0 {
+			rollups[i].PassRate = float64(rollups[i].PassedCases) / float64(rollups[i].TotalCases) * 100.0
+		}
+	}
+	return rollups, nil
+}
+
+// FindTestCase 在测试运行中查找测例，caseID 不为0时按ID查找，否则按名称查找
+// 测例不存在或不属于该测试运行时返回 ErrTestCaseNotFound
+func FindTestCase(c *gin.Context, testRunID, caseID uint64, name string) (*models.TestCase, error) {
 	var testCase models.TestCase
-	if err := models.DB.First(&testCase, id).Error; err != nil {
-		return nil, err
+	db := getDB(c)
+	query := db.Where("test_run_id = ?", testRunID)
+	if caseID != 0 {
+		query = query.Where("id = ?", caseID)
+	} else {
+		query = query.Where("name = ?", name)
+	}
+	if err := query.Order("id ASC").First(&testCase).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if caseID != 0 {
+				return nil, fmt.Errorf("%w: %d", ErrTestCaseNotFound, caseID)
+			}
+			return nil, fmt.Errorf("%w: %s", ErrTestCaseNotFound, name)
+		}
+		return nil, fmt.Errorf("failed to get test case: %w", err)
 	}
 	return &testCase, nil
 }
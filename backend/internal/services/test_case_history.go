@@ -0,0 +1,113 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultTestCaseHistoryLimit 测例历史默认返回的测试运行数量
+	DefaultTestCaseHistoryLimit = 30
+	// MaxTestCaseHistoryLimit 测例历史最多返回的测试运行数量
+	MaxTestCaseHistoryLimit = 200
+	// testCaseHistoryPreviewLength 测例历史中错误日志预览的最大长度
+	testCaseHistoryPreviewLength = 500
+)
+
+// TestCaseHistoryParams 测例历史查询参数
+type TestCaseHistoryParams struct {
+	Name      string // 测例完整名称（精确匹配）
+	ProjectID *uint64
+	Branch    string
+	TestType  string
+	Limit     int // 最近的测试运行数量
+}
+
+// TestCaseHistoryEntry 测例在一次测试运行中的结果
+type TestCaseHistoryEntry struct {
+	TestRunID       uint64                `json:"test_run_id"`
+	ProjectID       uint64                `json:"project_id"`
+	BranchName      string                `json:"branch_name"`
+	CommitID        string                `json:"commit_id"`
+	CommitShortID   string                `json:"commit_short_id"`
+	TestType        string                `json:"test_type"`
+	RunStatus       models.TestRunStatus  `json:"run_status"`
+	RunCreatedAt    time.Time             `json:"run_created_at"`
+	TestCaseID      uint64                `json:"test_case_id"`
+	Status          models.TestCaseStatus `json:"status"`
+	DurationMs      uint32                `json:"duration_ms"`
+	AttemptCount    uint16                `json:"attempt_count"`
+	PassedOnRetry   bool                  `json:"passed_on_retry"`
+	ErrorLogPreview string                `json:"error_log_preview,omitempty"`
+	ErrorLog        string                `json:"-"`
+}
+
+// TestCaseHistory 测例在最近测试运行中的结果，按测试运行时间从新到旧排列
+type TestCaseHistory struct {
+	Name         string                 `json:"name"`
+	Runs         []TestCaseHistoryEntry `json:"runs"`
+	PassedCount  int                    `json:"passed_count"`
+	FailedCount  int                    `json:"failed_count"`
+	SkippedCount int                    `json:"skipped_count"`
+}
+
+// GetTestCaseHistory 获取测例在最近 Limit 次公开测试运行中的状态、耗时和错误日志预览
+// 按测例名称精确匹配，使用 test_cases.name 索引；测试类型未注册时返回 ErrTestTypeNotFound
+func GetTestCaseHistory(c *gin.Context, params TestCaseHistoryParams) (*TestCaseHistory, error) {
+	limit := params.Limit
+	if limit < 1 {
+		limit = DefaultTestCaseHistoryLimit
+	}
+	if limit > MaxTestCaseHistoryLimit {
+		limit = MaxTestCaseHistoryLimit
+	}
+
+	db := getDB(c)
+	query := db.Table("test_cases").
+		Select("test_runs.id AS test_run_id, test_runs.project_id, test_runs.branch_name, "+
+			"test_runs.commit_id, test_runs.commit_short_id, test_runs.test_type, "+
+			"test_runs.status AS run_status, test_runs.created_at AS run_created_at, "+
+			"test_cases.id AS test_case_id, test_cases.status, test_cases.duration_ms, "+
+			"test_cases.attempt_count, test_cases.passed_on_retry, test_cases.error_log").
+		Joins("JOIN test_runs ON test_cases.test_run_id = test_runs.id").
+		Where("test_cases.name = ?", params.Name).
+		Where("test_runs.is_public = ?", true)
+
+	if params.ProjectID != nil {
+		query = query.Where("test_runs.project_id = ?", *params.ProjectID)
+	}
+	if params.Branch != "" {
+		query = query.Where("test_runs.branch_name = ?", params.Branch)
+	}
+	if params.TestType != "" {
+		if _, err := GetTestTypeByName(c, params.TestType); err != nil {
+			return nil, err
+		}
+		query = query.Where("test_runs.test_type = ?", params.TestType)
+	}
+
+	var entries []TestCaseHistoryEntry
+	if err := query.Order("test_runs.created_at DESC, test_runs.id DESC, test_cases.id ASC").
+		Limit(limit).
+		Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get test case history: %w", err)
+	}
+
+	history := &TestCaseHistory{Name: params.Name, Runs: make([]TestCaseHistoryEntry, 0, len(entries))}
+	for _, e := range entries {
+		e.ErrorLogPreview = truncateLog(e.ErrorLog, testCaseHistoryPreviewLength)
+		switch e.Status {
+		case models.TestCaseStatusPassed:
+			history.PassedCount++
+		case models.TestCaseStatusFailed:
+			history.FailedCount++
+		case models.TestCaseStatusSkipped:
+			history.SkippedCount++
+		}
+		history.Runs = append(history.Runs, e)
+	}
+	return history, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"gorm.io/gorm"
+)
+
+// 测例日志类型
+const (
+	TestCaseLogError = "error"
+	TestCaseLogDebug = "debug"
+)
+
+// insertTestCases 写入一批测例及其重试记录
+// 超过项目内联长度的日志只在数据库中保存预览，完整内容写入存储目录；
+// 返回已写入的日志文件路径，事务回滚时由调用方删除
+func insertTestCases(tx *gorm.DB, testRun *models.TestRun, inputs []TestCaseInput) ([]string, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	project, err := loadProjectSettings(tx, testRun.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	limit := int(project.LogInlineLimit)
+
+	cases := buildTestCases(testRun.ID, inputs, limit)
+	if err := tx.CreateInBatches(cases, 100).Error; err != nil {
+		return nil, fmt.Errorf("failed to create test cases: %w", err)
+	}
+	if err := addTestRunSummary(tx, testRun, cases); err != nil {
+		return nil, err
+	}
+
+	var attempts []models.TestCaseAttempt
+	for i := range cases {
+		for j, a := range inputs[i].Attempts {
+			// 每次尝试只保存日志预览，完整日志保存在最终结果中
+			attempts = append(attempts, models.TestCaseAttempt{
+				TestCaseID: cases[i].ID,
+				Attempt:    uint16(j + 1),
+				Status:     a.Status,
+				DurationMs: a.DurationMs,
+				ErrorLog:   truncateLog(a.ErrorLog, limit),
+				DebugLog:   truncateLog(a.DebugLog, limit),
+			})
+		}
+	}
+	if len(attempts) > 0 {
+		if err := tx.CreateInBatches(attempts, 100).Error; err != nil {
+			return nil, fmt.Errorf("failed to create test case attempts: %w", err)
+		}
+	}
+
+	var written []string
+	for i := range cases {
+		updates := map[string]interface{}{}
+		if cases[i].ErrorLogTruncated {
+			path, err := writeTestCaseLog(testRun.ID, cases[i].ID, TestCaseLogError, inputs[i].ErrorLog)
+			if err != nil {
+				return written, err
+			}
+			written = append(written, path)
+			updates["error_log_path"] = path
+		}
+		if cases[i].DebugLogTruncated {
+			path, err := writeTestCaseLog(testRun.ID, cases[i].ID, TestCaseLogDebug, inputs[i].DebugLog)
+			if err != nil {
+				return written, err
+			}
+			written = append(written, path)
+			updates["debug_log_path"] = path
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&cases[i]).Updates(updates).Error; err != nil {
+				return written, fmt.Errorf("failed to update test case log path: %w", err)
+			}
+		}
+	}
+
+	return written, nil
+}
+
+// writeTestCaseLog 将完整日志写入测试运行的存储目录
+func writeTestCaseLog(testRunID, testCaseID uint64, kind, content string) (string, error) {
+	logDir := filepath.Join(testRunStorageDir(testRunID), "logs")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	filePath := filepath.Join(logDir, fmt.Sprintf("case_%d_%s.log", testCaseID, kind))
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write test case log: %w", err)
+	}
+	return filePath, nil
+}
+
+// removeFiles 删除文件，用于事务回滚后清理已写入的日志
+func removeFiles(paths []string) {
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
+
+// truncateLog 将日志截断到不超过 limit 字节，不会截断UTF-8字符
+func truncateLog(log string, limit int) string {
+	if len(log) <= limit {
+		return log
+	}
+	for limit > 0 && !utf8.RuneStart(log[limit]) {
+		limit--
+	}
+	return log[:limit]
+}
+
+// OpenTestCaseLog 打开测例的完整日志
+// 日志被截断时读取存储目录中的完整内容，否则直接返回数据库中的内容
+func OpenTestCaseLog(testCase *models.TestCase, kind string) (io.ReadCloser, int64, error) {
+	var inline, path string
+	var truncated bool
+	switch kind {
+	case TestCaseLogError:
+		inline, path, truncated = testCase.ErrorLog, testCase.ErrorLogPath, testCase.ErrorLogTruncated
+	case TestCaseLogDebug:
+		inline, path, truncated = testCase.DebugLog, testCase.DebugLogPath, testCase.DebugLogTruncated
+	default:
+		return nil, 0, ErrInvalidLogKind
+	}
+
+	if !truncated || path == "" {
+		return io.NopCloser(strings.NewReader(inline)), int64(len(inline)), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open test case log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("failed to stat test case log: %w", err)
+	}
+	return file, info.Size(), nil
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+)
+
+func TestMergeRetriedCases(t *testing.T) {
+	tests := []struct {
+		name  string
+		cases []TestCaseInput
+		want  []TestCaseInput
+	}{
+		{
+			name: "no retries",
+			cases: []TestCaseInput{
+				{Name: "ReadTest.ReadEmptyFile", Status: models.TestCaseStatusPassed, DurationMs: 12},
+				{Name: "ReadTest.ReadWithOffset", Status: models.TestCaseStatusFailed, ErrorLog: "read.cc:52"},
+			},
+			want: []TestCaseInput{
+				{Name: "ReadTest.ReadEmptyFile", Status: models.TestCaseStatusPassed, DurationMs: 12},
+				{Name: "ReadTest.ReadWithOffset", Status: models.TestCaseStatusFailed, ErrorLog: "read.cc:52"},
+			},
+		},
+		{
+			name: "retried test case keeps its first position",
+			cases: []TestCaseInput{
+				{Name: "PipeTest.Flaky", Status: models.TestCaseStatusFailed, DurationMs: 30, ErrorLog: "timeout", DebugLog: "first"},
+				{Name: "PipeTest.Stable", Status: models.TestCaseStatusPassed, DurationMs: 5},
+				{Name: "PipeTest.Flaky", Status: models.TestCaseStatusFailed, DurationMs: 20, ErrorLog: "timeout again"},
+				{Name: "PipeTest.Flaky", Status: models.TestCaseStatusPassed, DurationMs: 10, DebugLog: "last"},
+			},
+			want: []TestCaseInput{
+				{Name: "PipeTest.Flaky", Attempts: []TestCaseAttemptInput{
+					{Status: models.TestCaseStatusFailed, DurationMs: 30, ErrorLog: "timeout", DebugLog: "first"},
+					{Status: models.TestCaseStatusFailed, DurationMs: 20, ErrorLog: "timeout again"},
+					{Status: models.TestCaseStatusPassed, DurationMs: 10, DebugLog: "last"},
+				}},
+				{Name: "PipeTest.Stable", Status: models.TestCaseStatusPassed, DurationMs: 5},
+			},
+		},
+		{
+			name:  "empty input",
+			cases: []TestCaseInput{},
+			want:  []TestCaseInput{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeRetriedCases(tt.cases)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeRetriedCases() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyAttempts(t *testing.T) {
+	tests := []struct {
+		name string
+		tc   TestCaseInput
+		want TestCaseInput
+	}{
+		{
+			name: "without attempts",
+			tc:   TestCaseInput{Name: "A.b", Status: models.TestCaseStatusPassed, DurationMs: 3},
+			want: TestCaseInput{Name: "A.b", Status: models.TestCaseStatusPassed, DurationMs: 3},
+		},
+		{
+			name: "result is inferred from attempts",
+			tc: TestCaseInput{Name: "A.b", Attempts: []TestCaseAttemptInput{
+				{Status: models.TestCaseStatusFailed, DurationMs: 30, ErrorLog: "first failure", DebugLog: "run 1"},
+				{Status: models.TestCaseStatusFailed, DurationMs: 20, ErrorLog: "second failure", DebugLog: "run 2"},
+				{Status: models.TestCaseStatusPassed, DurationMs: 10, DebugLog: "run 3"},
+			}},
+			want: TestCaseInput{
+				Name:       "A.b",
+				Status:     models.TestCaseStatusPassed,
+				DurationMs: 60,
+				ErrorLog:   "second failure",
+				DebugLog:   "run 3",
+				Attempts: []TestCaseAttemptInput{
+					{Status: models.TestCaseStatusFailed, DurationMs: 30, ErrorLog: "first failure", DebugLog: "run 1"},
+					{Status: models.TestCaseStatusFailed, DurationMs: 20, ErrorLog: "second failure", DebugLog: "run 2"},
+					{Status: models.TestCaseStatusPassed, DurationMs: 10, DebugLog: "run 3"},
+				},
+			},
+		},
+		{
+			name: "explicit fields are kept",
+			tc: TestCaseInput{Name: "A.b", Status: models.TestCaseStatusFailed, DurationMs: 100, ErrorLog: "summary", Attempts: []TestCaseAttemptInput{
+				{Status: models.TestCaseStatusFailed, DurationMs: 30, ErrorLog: "timeout"},
+			}},
+			want: TestCaseInput{Name: "A.b", Status: models.TestCaseStatusFailed, DurationMs: 100, ErrorLog: "summary", Attempts: []TestCaseAttemptInput{
+				{Status: models.TestCaseStatusFailed, DurationMs: 30, ErrorLog: "timeout"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.tc
+			if err := applyAttempts(&got); err != nil {
+				t.Fatalf("applyAttempts() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyAttempts() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyAttemptsInvalid(t *testing.T) {
+	tooMany := make([]TestCaseAttemptInput, MaxTestCaseAttempts+1)
+	for i := range tooMany {
+		tooMany[i] = TestCaseAttemptInput{Status: models.TestCaseStatusFailed}
+	}
+
+	tests := []struct {
+		name string
+		tc   TestCaseInput
+	}{
+		{name: "too many attempts", tc: TestCaseInput{Name: "A.b", Attempts: tooMany}},
+		{name: "invalid attempt status", tc: TestCaseInput{Name: "A.b", Attempts: []TestCaseAttemptInput{{Status: "unknown"}}}},
+		{name: "status does not match last attempt", tc: TestCaseInput{Name: "A.b", Status: models.TestCaseStatusPassed, Attempts: []TestCaseAttemptInput{
+			{Status: models.TestCaseStatusPassed},
+			{Status: models.TestCaseStatusFailed},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := applyAttempts(&tt.tc)
+			if !errors.Is(err, ErrInvalidTestRunUpload) {
+				t.Errorf("applyAttempts() error = %v, want %v", err, ErrInvalidTestRunUpload)
+			}
+		})
+	}
+}
+
+func TestPassedOnRetry(t *testing.T) {
+	tests := []struct {
+		name string
+		tc   TestCaseInput
+		want bool
+	}{
+		{
+			name: "passed after failure",
+			tc: TestCaseInput{Status: models.TestCaseStatusPassed, Attempts: []TestCaseAttemptInput{
+				{Status: models.TestCaseStatusFailed},
+				{Status: models.TestCaseStatusPassed},
+			}},
+			want: true,
+		},
+		{
+			name: "passed without attempts",
+			tc:   TestCaseInput{Status: models.TestCaseStatusPassed},
+			want: false,
+		},
+		{
+			name: "passed after skip",
+			tc: TestCaseInput{Status: models.TestCaseStatusPassed, Attempts: []TestCaseAttemptInput{
+				{Status: models.TestCaseStatusSkipped},
+				{Status: models.TestCaseStatusPassed},
+			}},
+			want: false,
+		},
+		{
+			name: "failed on every attempt",
+			tc: TestCaseInput{Status: models.TestCaseStatusFailed, Attempts: []TestCaseAttemptInput{
+				{Status: models.TestCaseStatusFailed},
+				{Status: models.TestCaseStatusFailed},
+			}},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := passedOnRetry(&tt.tc); got != tt.want {
+				t.Errorf("passedOnRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTestCaseName(t *testing.T) {
+	tests := []struct {
+		name      string
+		wantSuite string
+		wantTest  string
+		wantParam string
+	}{
+		{name: "ReadTest.ReadEmptyFile", wantSuite: "ReadTest", wantTest: "ReadEmptyFile"},
+		{
+			name:      "SocketInetLoopbackTest/SocketInetReusePortTest.TcpPortReuseMultiThread/0",
+			wantSuite: "SocketInetLoopbackTest/SocketInetReusePortTest",
+			wantTest:  "TcpPortReuseMultiThread",
+			wantParam: "0",
+		},
+		{name: "Suite.Test/param.x", wantSuite: "Suite", wantTest: "Test", wantParam: "param.x"},
+		{name: "pkg.Class.test", wantSuite: "pkg.Class", wantTest: "test"},
+		{name: "pkg.Class.test[1.5]", wantSuite: "pkg.Class", wantTest: "test", wantParam: "1.5"},
+		{name: "pkg.Class.test[a/b.c]", wantSuite: "pkg.Class", wantTest: "test", wantParam: "a/b.c"},
+		{name: "test[1]", wantTest: "test", wantParam: "1"},
+		{name: "standalone", wantTest: "standalone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite, test, param := splitTestCaseName(tt.name)
+			if suite != tt.wantSuite || test != tt.wantTest || param != tt.wantParam {
+				t.Errorf("splitTestCaseName() = (%q, %q, %q), want (%q, %q, %q)",
+					suite, test, param, tt.wantSuite, tt.wantTest, tt.wantParam)
+			}
+		})
+	}
+}
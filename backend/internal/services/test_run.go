@@ -8,6 +8,7 @@ import (
 	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // TestRunQueryParams 测试运行查询参数
@@ -19,29 +20,11 @@ type TestRunQueryParams struct {
 	EndTime      *time.Time
 	Status       string
 	TestCaseName string
+	Metadata     map[string]string // 元数据过滤，所有键值都必须匹配
 	Page         int
 	PageSize     int
 }
 
-// CreateTestRun 创建测试运行
-func CreateTestRun(c *gin.Context, projectID uint64, branchName, commitID, commitShortID, testType string) (*models.TestRun, error) {
-	testRun := &models.TestRun{
-		ProjectID:     projectID,
-		BranchName:    branchName,
-		CommitID:      commitID,
-		CommitShortID: commitShortID,
-		TestType:      testType,
-		Status:        models.TestRunStatusRunning,
-	}
-
-	db := getDB(c)
-	if err := db.Create(testRun).Error; err != nil {
-		return nil, fmt.Errorf("failed to create test run: %w", err)
-	}
-
-	return testRun, nil
-}
-
 // GetTestRunByID 根据ID获取测试运行
 func GetTestRunByID(c *gin.Context, id uint64) (*models.TestRun, error) {
 	var testRun models.TestRun
@@ -49,6 +32,22 @@ func GetTestRunByID(c *gin.Context, id uint64) (*models.TestRun, error) {
 	if err := db.Preload("Project").
 		Preload("TestCases").
 		Preload("OutputFiles").
+		Preload("Metadata").
+		Preload("Commit").
+		First(&testRun, id).Error; err != nil {
+		return nil, err
+	}
+	return &testRun, nil
+}
+
+// GetTestRunWithoutCases 根据ID获取测试运行，只加载项目、元数据和提交，不加载测例和输出文件
+// 用于只需要测试运行本身的比较和分析接口
+func GetTestRunWithoutCases(c *gin.Context, id uint64) (*models.TestRun, error) {
+	var testRun models.TestRun
+	db := getDB(c)
+	if err := db.Preload("Project").
+		Preload("Metadata").
+		Preload("Commit").
 		First(&testRun, id).Error; err != nil {
 		return nil, err
 	}
@@ -57,12 +56,13 @@ func GetTestRunByID(c *gin.Context, id uint64) (*models.TestRun, error) {
 
 // QueryTestRuns 查询测试运行列表
 // includePrivate 为true时包含私有记录（管理员使用），为false时只返回公开记录（公开接口使用）
+// 测试类型未注册时返回 ErrTestTypeNotFound，元数据过滤条件不合法时返回 ErrInvalidMetadataFilter
 func QueryTestRuns(c *gin.Context, params TestRunQueryParams, includePrivate bool) ([]models.TestRun, int64, error) {
 	var testRuns []models.TestRun
 	var total int64
 
 	db := getDB(c)
-	query := db.Model(&models.TestRun{}).Preload("Project")
+	query := db.Model(&models.TestRun{}).Preload("Project").Preload("Metadata").Preload("Commit")
 
 	// 如果不是管理员查询，只返回公开的记录
 	if !includePrivate {
@@ -79,8 +79,11 @@ func QueryTestRuns(c *gin.Context, params TestRunQueryParams, includePrivate boo
 		query = query.Where("commit_id LIKE ? OR commit_short_id LIKE ?", params.CommitID+"%", params.CommitID+"%")
 	}
 
-	// 测试类型过滤
+	// 测试类型过滤，只接受已注册的测试类型
 	if params.TestType != "" {
+		if _, err := GetTestTypeByName(c, params.TestType); err != nil {
+			return nil, 0, err
+		}
 		query = query.Where("test_type = ?", params.TestType)
 	}
 
@@ -103,6 +106,17 @@ func QueryTestRuns(c *gin.Context, params TestRunQueryParams, includePrivate boo
 			Where("test_cases.name LIKE ?", "%"+params.TestCaseName+"%")
 	}
 
+	// 元数据过滤（精确匹配）
+	if len(params.Metadata) > 0 {
+		if err := validateMetadata(params.Metadata); err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrInvalidMetadataFilter, err)
+		}
+		for _, m := range buildTestRunMetadata(0, params.Metadata) {
+			query = query.Where("EXISTS (SELECT 1 FROM test_run_metadata WHERE test_run_metadata.test_run_id = test_runs.id AND test_run_metadata.meta_key = ? AND test_run_metadata.meta_value = ?)",
+				m.Key, m.Value)
+		}
+	}
+
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -130,28 +144,101 @@ func QueryTestRuns(c *gin.Context, params TestRunQueryParams, includePrivate boo
 	return testRuns, total, nil
 }
 
-// UpdateTestRunStatus 更新测试运行状态
-func UpdateTestRunStatus(c *gin.Context, id uint64, status models.TestRunStatus) error {
-	testRun, err := GetTestRunByID(c, id)
+// CompleteTestRun 完成测试运行，reason 为可选的状态说明（如 error 状态的故障原因）
+// 锁定测试运行记录以避免与追加测例并发
+func CompleteTestRun(c *gin.Context, id uint64, status models.TestRunStatus, reason string) error {
+	db := getDB(c)
+	return db.Transaction(func(tx *gorm.DB) error {
+		testRun, err := lockTestRun(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := CheckProjectAccess(c, testRun.ProjectID); err != nil {
+			return err
+		}
+		testRun.StatusReason = reason
+		return transitionTestRun(tx, testRun, status)
+	})
+}
+
+// HeartbeatTestRun 记录运行中测试的心跳，避免长时间运行的测试被自动取消
+func HeartbeatTestRun(c *gin.Context, id uint64) (*models.TestRun, error) {
+	var testRun *models.TestRun
+	db := getDB(c)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		testRun, err = lockTestRun(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := CheckProjectAccess(c, testRun.ProjectID); err != nil {
+			return err
+		}
+		if testRun.Status != models.TestRunStatusRunning {
+			return ErrTestRunNotRunning
+		}
+
+		testRun.Touch()
+		if err := tx.Model(testRun).Update("last_activity_at", testRun.LastActivityAt).Error; err != nil {
+			return fmt.Errorf("failed to update test run activity: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return testRun, nil
+}
 
-	testRun.Complete(status)
-	db := getDB(c)
-	return db.Save(testRun).Error
+// transitionTestRun 在事务中将测试运行转换到目标状态
+// 所有状态变更都经过该函数，只允许状态机中定义的转换，否则返回 ErrInvalidStatusTransition
+func transitionTestRun(tx *gorm.DB, testRun *models.TestRun, status models.TestRunStatus) error {
+	return transitionTestRunAt(tx, testRun, status, time.Now())
 }
 
-// CompleteTestRun 完成测试运行
-func CompleteTestRun(c *gin.Context, id uint64, status models.TestRunStatus) error {
-	testRun, err := GetTestRunByID(c, id)
-	if err != nil {
+// transitionTestRunAt 以指定的完成时间转换测试运行状态，用于导入历史数据
+func transitionTestRunAt(tx *gorm.DB, testRun *models.TestRun, status models.TestRunStatus, completedAt time.Time) error {
+	if !testRun.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, testRun.Status, status)
+	}
+
+	// 结束时根据测例重新统计，保证统计与最终的测例一致
+	if err := refreshTestRunSummary(tx, testRun); err != nil {
 		return err
 	}
+	testRun.CompleteAt(status, completedAt)
+	if err := tx.Save(testRun).Error; err != nil {
+		return fmt.Errorf("failed to update test run status: %w", err)
+	}
+	return afterTestRunCompleted(tx, testRun)
+}
 
-	testRun.Complete(status)
-	db := getDB(c)
-	return db.Save(testRun).Error
+// afterTestRunCompleted 测试运行结束后登记依赖运行历史的分析数据（不稳定度、回归），事务提交后由后台任务计算
+// 只有公开且有测例结果（passed / failed）的运行参与分析
+func afterTestRunCompleted(tx *gorm.DB, testRun *models.TestRun) error {
+	if !testRun.IsPublic {
+		return nil
+	}
+	return queueTestRunAnalytics(tx, testRun)
+}
+
+// analyzedTestRuns 返回参与运行历史分析的测试运行查询：同一项目、分支和测试类型下公开且已完成（passed / failed）的运行
+func analyzedTestRuns(tx *gorm.DB, projectID uint64, branch, testType string) *gorm.DB {
+	return tx.Model(&models.TestRun{}).
+		Where("project_id = ? AND branch_name = ? AND test_type = ? AND is_public = ? AND status IN ?",
+			projectID, branch, testType, true, []models.TestRunStatus{models.TestRunStatusPassed, models.TestRunStatusFailed})
+}
+
+// lockTestRun 在事务中获取并锁定测试运行记录
+func lockTestRun(tx *gorm.DB, id uint64) (*models.TestRun, error) {
+	var testRun models.TestRun
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&testRun, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTestRunNotFound
+		}
+		return nil, fmt.Errorf("failed to get test run: %w", err)
+	}
+	return &testRun, nil
 }
 
 // MasterBranchStats master分支统计信息
@@ -169,6 +256,8 @@ type MasterBranchStats struct {
 	SkippedCases  int64     `json:"skipped_cases"`
 	PassRate      float64   `json:"pass_rate"`
 	Duration      int64     `json:"duration"` // 总耗时（毫秒）
+
+	PassedOnRetryCases int64 `json:"passed_on_retry_cases"` // 重试后通过的测例数（包含在 PassedCases 中）
 }
 
 // GetMasterBranchLatestStats 获取master分支最新的测试统计数据
@@ -177,65 +266,18 @@ func GetMasterBranchLatestStats(c *gin.Context) (*MasterBranchStats, error) {
 
 	// 查找master分支最新的已完成测试运行（只返回公开的记录）
 	var testRun models.TestRun
-	if err := db.Where("branch_name = ? AND status IN (?) AND is_public = ?", "master", []models.TestRunStatus{
+	if err := db.Where("branch_name = ? AND status IN (?) AND is_public = ?", models.MasterBranch, []models.TestRunStatus{
 		models.TestRunStatusPassed,
 		models.TestRunStatusFailed,
 	}, true).Order("created_at DESC").First(&testRun).Error; err != nil {
 		// 如果没有找到已完成的，尝试找运行中的（只返回公开的记录）
-		if err := db.Where("branch_name = ? AND is_public = ?", "master", true).
+		if err := db.Where("branch_name = ? AND is_public = ?", models.MasterBranch, true).
 			Order("created_at DESC").First(&testRun).Error; err != nil {
 			return nil, fmt.Errorf("no test run found for master branch")
 		}
 	}
 
-	// 统计测例数据
-	var totalCases, passedCases, failedCases, skippedCases int64
-	var duration int64
-
-	if err := db.Model(&models.TestCase{}).
-		Where("test_run_id = ?", testRun.ID).
-		Count(&totalCases).Error; err != nil {
-		return nil, fmt.Errorf("failed to count test cases: %w", err)
-	}
-
-	if totalCases > 0 {
-		if err := db.Model(&models.TestCase{}).
-			Where("test_run_id = ? AND status = ?", testRun.ID, models.TestCaseStatusPassed).
-			Count(&passedCases).Error; err != nil {
-			return nil, fmt.Errorf("failed to count passed cases: %w", err)
-		}
-
-		if err := db.Model(&models.TestCase{}).
-			Where("test_run_id = ? AND status = ?", testRun.ID, models.TestCaseStatusFailed).
-			Count(&failedCases).Error; err != nil {
-			return nil, fmt.Errorf("failed to count failed cases: %w", err)
-		}
-
-		if err := db.Model(&models.TestCase{}).
-			Where("test_run_id = ? AND status = ?", testRun.ID, models.TestCaseStatusSkipped).
-			Count(&skippedCases).Error; err != nil {
-			return nil, fmt.Errorf("failed to count skipped cases: %w", err)
-		}
-
-		// 计算总耗时
-		var result struct {
-			TotalDuration int64
-		}
-		if err := db.Model(&models.TestCase{}).
-			Select("COALESCE(SUM(duration_ms), 0) as total_duration").
-			Where("test_run_id = ?", testRun.ID).
-			Scan(&result).Error; err != nil {
-			return nil, fmt.Errorf("failed to calculate duration: %w", err)
-		}
-		duration = result.TotalDuration
-	}
-
-	// 计算通过率
-	passRate := 0.0
-	if totalCases > 0 {
-		passRate = float64(passedCases) / float64(totalCases) * 100.0
-	}
-
+	// 测例统计使用测试运行上保存的计数
 	stats := &MasterBranchStats{
 		TestRunID:     testRun.ID,
 		BranchName:    testRun.BranchName,
@@ -244,12 +286,14 @@ func GetMasterBranchLatestStats(c *gin.Context) (*MasterBranchStats, error) {
 		TestType:      testRun.TestType,
 		Status:        string(testRun.Status),
 		CreatedAt:     testRun.CreatedAt,
-		TotalCases:    totalCases,
-		PassedCases:   passedCases,
-		FailedCases:   failedCases,
-		SkippedCases:  skippedCases,
-		PassRate:      passRate,
-		Duration:      duration,
+		TotalCases:    int64(testRun.TotalCases),
+		PassedCases:   int64(testRun.PassedCases),
+		FailedCases:   int64(testRun.FailedCases),
+		SkippedCases:  int64(testRun.SkippedCases),
+		PassRate:      testRun.PassRate(),
+		Duration:      int64(testRun.TotalDurationMs),
+
+		PassedOnRetryCases: int64(testRun.PassedOnRetryCases),
 	}
 
 	return stats, nil
@@ -465,42 +509,53 @@ func GetDashboardTrend(c *gin.Context, days int) ([]TrendData, error) {
 }
 
 // DeleteTestRun 删除测试运行
+// 删除公开的测试运行后重新计算其所在范围的分析数据
 func DeleteTestRun(c *gin.Context, id uint64) error {
 	db := getDB(c)
-
-	// 检查测试运行是否存在
-	var testRun models.TestRun
-	if err := db.First(&testRun, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrTestRunNotFound
+	return db.Transaction(func(tx *gorm.DB) error {
+		testRun, err := lockTestRun(tx, id)
+		if err != nil {
+			return err
 		}
-		return fmt.Errorf("failed to get test run: %w", err)
-	}
 
-	// 由于外键约束，删除测试运行会自动删除关联的测例和输出文件
-	if err := db.Delete(&testRun).Error; err != nil {
-		return fmt.Errorf("failed to delete test run: %w", err)
-	}
+		// 删除前标记引用该运行的未关闭回归需要重新检查
+		if testRun.IsPublic {
+			if err := invalidateRegressions(tx, testRun); err != nil {
+				return err
+			}
+		}
 
-	return nil
+		// 由于外键约束，删除测试运行会自动删除关联的测例和输出文件
+		if err := tx.Delete(testRun).Error; err != nil {
+			return fmt.Errorf("failed to delete test run: %w", err)
+		}
+		if !testRun.IsPublic {
+			return nil
+		}
+		return queueTestRunAnalytics(tx, testRun)
+	})
 }
 
 // UpdateTestRunVisibility 更新测试运行的可见性
+// 只有公开的测试运行参与分析，可见性变化后重新计算其所在范围的分析数据
 func UpdateTestRunVisibility(c *gin.Context, id uint64, isPublic bool) error {
 	db := getDB(c)
-
-	var testRun models.TestRun
-	if err := db.First(&testRun, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrTestRunNotFound
+	return db.Transaction(func(tx *gorm.DB) error {
+		testRun, err := lockTestRun(tx, id)
+		if err != nil {
+			return err
+		}
+		if testRun.IsPublic == isPublic {
+			return nil
 		}
-		return fmt.Errorf("failed to get test run: %w", err)
-	}
-
-	testRun.IsPublic = isPublic
-	if err := db.Save(&testRun).Error; err != nil {
-		return fmt.Errorf("failed to update test run visibility: %w", err)
-	}
 
-	return nil
+		// 只更新可见性，避免覆盖同时追加测例时更新的统计
+		if err := tx.Model(testRun).Update("is_public", isPublic).Error; err != nil {
+			return fmt.Errorf("failed to update test run visibility: %w", err)
+		}
+		if err := invalidateRegressions(tx, testRun); err != nil {
+			return err
+		}
+		return queueTestRunAnalytics(tx, testRun)
+	})
 }
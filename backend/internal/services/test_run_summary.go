@@ -0,0 +1,107 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// addTestRunSummary 将新写入的测例累加到测试运行的统计中
+// 数据库中使用增量更新，并发追加测例时不会丢失计数；同时更新内存中的测试运行，避免之后保存时覆盖
+func addTestRunSummary(tx *gorm.DB, testRun *models.TestRun, cases []models.TestCase) error {
+	var delta models.TestRunSummary
+	for i := range cases {
+		delta.Add(&cases[i])
+	}
+	if delta.TotalCases == 0 {
+		return nil
+	}
+
+	if err := tx.Model(&models.TestRun{}).Where("id = ?", testRun.ID).UpdateColumns(map[string]interface{}{
+		"total_cases":           gorm.Expr("total_cases + ?", delta.TotalCases),
+		"passed_cases":          gorm.Expr("passed_cases + ?", delta.PassedCases),
+		"failed_cases":          gorm.Expr("failed_cases + ?", delta.FailedCases),
+		"skipped_cases":         gorm.Expr("skipped_cases + ?", delta.SkippedCases),
+		"passed_on_retry_cases": gorm.Expr("passed_on_retry_cases + ?", delta.PassedOnRetryCases),
+		"total_duration_ms":     gorm.Expr("total_duration_ms + ?", delta.TotalDurationMs),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update test run summary: %w", err)
+	}
+	testRun.TestRunSummary.Merge(delta)
+	return nil
+}
+
+// refreshTestRunSummary 根据测例重新统计测试运行，测试运行结束和回填时使用
+func refreshTestRunSummary(tx *gorm.DB, testRun *models.TestRun) error {
+	var summary models.TestRunSummary
+	if err := tx.Model(&models.TestCase{}).
+		Select("COUNT(*) AS total_cases, "+
+			"COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS passed_cases, "+
+			"COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS failed_cases, "+
+			"COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS skipped_cases, "+
+			"COALESCE(SUM(CASE WHEN passed_on_retry THEN 1 ELSE 0 END), 0) AS passed_on_retry_cases, "+
+			"COALESCE(SUM(duration_ms), 0) AS total_duration_ms",
+			models.TestCaseStatusPassed, models.TestCaseStatusFailed, models.TestCaseStatusSkipped).
+		Where("test_run_id = ?", testRun.ID).
+		Scan(&summary).Error; err != nil {
+		return fmt.Errorf("failed to count test cases: %w", err)
+	}
+
+	if err := tx.Model(&models.TestRun{}).Where("id = ?", testRun.ID).UpdateColumns(map[string]interface{}{
+		"total_cases":           summary.TotalCases,
+		"passed_cases":          summary.PassedCases,
+		"failed_cases":          summary.FailedCases,
+		"skipped_cases":         summary.SkippedCases,
+		"passed_on_retry_cases": summary.PassedOnRetryCases,
+		"total_duration_ms":     summary.TotalDurationMs,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update test run summary: %w", err)
+	}
+	testRun.TestRunSummary = summary
+	return nil
+}
+
+// BackfillTestRunSummaries 按ID顺序分批重新统计所有测试运行，返回处理的测试运行数量
+// 用于统计与测例不一致时修正，可以重复执行；progress 不为空时每批处理完后调用
+func BackfillTestRunSummaries(c *gin.Context, batchSize int, progress func(done int)) (int, error) {
+	if batchSize < 1 {
+		batchSize = 500
+	}
+
+	db := getDB(c)
+	var lastID uint64
+	done := 0
+	for {
+		var testRuns []models.TestRun
+		if err := db.Select("id").
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&testRuns).Error; err != nil {
+			return done, fmt.Errorf("failed to get test runs: %w", err)
+		}
+		if len(testRuns) == 0 {
+			return done, nil
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for i := range testRuns {
+				if err := refreshTestRunSummary(tx, &testRuns[i]); err != nil {
+					return fmt.Errorf("test run %d: %w", testRuns[i].ID, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return done, err
+		}
+
+		done += len(testRuns)
+		lastID = testRuns[len(testRuns)-1].ID
+		if progress != nil {
+			progress(done)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListTestTypes 列出所有已注册的测试类型
+func ListTestTypes(c *gin.Context) ([]models.TestTypeDefinition, error) {
+	var testTypes []models.TestTypeDefinition
+	db := getDB(c)
+	if err := db.Preload("AllowedProjects").Order("name ASC").Find(&testTypes).Error; err != nil {
+		return nil, err
+	}
+	return testTypes, nil
+}
+
+// PublicTestType 公开接口返回的测试类型，只包含允许使用的项目ID
+type PublicTestType struct {
+	ID                uint64   `json:"id"`
+	Name              string   `json:"name"`
+	DisplayName       string   `json:"display_name"`
+	Description       string   `json:"description"`
+	AllowedProjectIDs []uint64 `json:"allowed_project_ids"` // 为空表示所有项目都可以使用
+}
+
+// ListPublicTestTypes 列出所有已注册的测试类型（公开接口使用）
+func ListPublicTestTypes(c *gin.Context) ([]PublicTestType, error) {
+	testTypes, err := ListTestTypes(c)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]PublicTestType, 0, len(testTypes))
+	for _, tt := range testTypes {
+		projectIDs := make([]uint64, 0, len(tt.AllowedProjects))
+		for _, p := range tt.AllowedProjects {
+			projectIDs = append(projectIDs, p.ID)
+		}
+		result = append(result, PublicTestType{
+			ID:                tt.ID,
+			Name:              tt.Name,
+			DisplayName:       tt.DisplayName,
+			Description:       tt.Description,
+			AllowedProjectIDs: projectIDs,
+		})
+	}
+	return result, nil
+}
+
+// GetTestTypeByName 根据名称获取测试类型
+func GetTestTypeByName(c *gin.Context, name string) (*models.TestTypeDefinition, error) {
+	var testType models.TestTypeDefinition
+	db := getDB(c)
+	if err := db.Preload("AllowedProjects").Where("name = ?", name).First(&testType).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrTestTypeNotFound, name)
+		}
+		return nil, fmt.Errorf("failed to get test type: %w", err)
+	}
+	return &testType, nil
+}
+
+// ValidateTestType 检查测试类型是否已注册且允许该项目使用
+func ValidateTestType(c *gin.Context, name string, projectID uint64) error {
+	testType, err := GetTestTypeByName(c, name)
+	if err != nil {
+		return err
+	}
+	if !testType.AllowsProject(projectID) {
+		return fmt.Errorf("%w: %s", ErrTestTypeNotAllowed, name)
+	}
+	return nil
+}
+
+// CreateTestType 注册测试类型
+func CreateTestType(c *gin.Context, name, displayName, description string, projectIDs []uint64) (*models.TestTypeDefinition, error) {
+	db := getDB(c)
+
+	var existing models.TestTypeDefinition
+	if err := db.Where("name = ?", name).First(&existing).Error; err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTestTypeExists, name)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check test type existence: %w", err)
+	}
+
+	projects, err := loadProjects(db, projectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	testType := &models.TestTypeDefinition{
+		Name:            name,
+		DisplayName:     displayName,
+		Description:     description,
+		AllowedProjects: projects,
+	}
+	// 只写入关联关系，不更新项目本身
+	if err := db.Omit("AllowedProjects.*").Create(testType).Error; err != nil {
+		return nil, fmt.Errorf("failed to create test type: %w", err)
+	}
+
+	return testType, nil
+}
+
+// UpdateTestType 更新测试类型
+// 名称被测试运行引用，不允许修改
+func UpdateTestType(c *gin.Context, id uint64, displayName, description string, projectIDs []uint64) (*models.TestTypeDefinition, error) {
+	db := getDB(c)
+
+	var testType models.TestTypeDefinition
+	if err := db.First(&testType, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTestTypeNotFound
+		}
+		return nil, fmt.Errorf("failed to get test type: %w", err)
+	}
+
+	projects, err := loadProjects(db, projectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		testType.DisplayName = displayName
+		testType.Description = description
+		if err := tx.Save(&testType).Error; err != nil {
+			return fmt.Errorf("failed to update test type: %w", err)
+		}
+		if err := tx.Model(&testType).Association("AllowedProjects").Replace(projects); err != nil {
+			return fmt.Errorf("failed to update allowed projects: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	testType.AllowedProjects = projects
+	return &testType, nil
+}
+
+// DeleteTestType 删除测试类型
+// 仍有测试运行使用该类型时返回 ErrTestTypeInUse
+func DeleteTestType(c *gin.Context, id uint64) error {
+	db := getDB(c)
+
+	var testType models.TestTypeDefinition
+	if err := db.First(&testType, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTestTypeNotFound
+		}
+		return fmt.Errorf("failed to get test type: %w", err)
+	}
+
+	var runCount int64
+	if err := db.Model(&models.TestRun{}).Where("test_type = ?", testType.Name).Count(&runCount).Error; err != nil {
+		return fmt.Errorf("failed to count test runs: %w", err)
+	}
+	if runCount > 0 {
+		return fmt.Errorf("%w: %d test runs", ErrTestTypeInUse, runCount)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&testType).Association("AllowedProjects").Clear(); err != nil {
+			return fmt.Errorf("failed to clear allowed projects: %w", err)
+		}
+		if err := tx.Delete(&testType).Error; err != nil {
+			return fmt.Errorf("failed to delete test type: %w", err)
+		}
+		return nil
+	})
+}
+
+// loadProjects 根据ID列表加载项目，任意一个不存在时返回 ErrProjectNotFound
+func loadProjects(db *gorm.DB, projectIDs []uint64) ([]models.Project, error) {
+	if len(projectIDs) == 0 {
+		return []models.Project{}, nil
+	}
+
+	var projects []models.Project
+	if err := db.Where("id IN ?", projectIDs).Find(&projects).Error; err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+
+	found := make(map[uint64]bool, len(projects))
+	for _, p := range projects {
+		found[p.ID] = true
+	}
+	for _, id := range projectIDs {
+		if !found[id] {
+			return nil, fmt.Errorf("%w: %d", ErrProjectNotFound, id)
+		}
+	}
+	return projects, nil
+}
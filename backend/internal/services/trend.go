@@ -0,0 +1,173 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dragonos/dragonos-ci-dashboard/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 通过率趋势的时间粒度
+const (
+	TrendBucketHour = "hour"
+	TrendBucketDay  = "day"
+	TrendBucketWeek = "week"
+)
+
+// trendBucketSpec 时间粒度对应的分组表达式、默认时间范围和最大时间范围
+type trendBucketSpec struct {
+	expr       string
+	defaultLen time.Duration
+	maxLen     time.Duration
+}
+
+var trendBuckets = map[string]trendBucketSpec{
+	TrendBucketHour: {
+		expr:       "DATE_FORMAT(test_runs.created_at, '%Y-%m-%d %H:00')",
+		defaultLen: 48 * time.Hour,
+		maxLen:     14 * 24 * time.Hour,
+	},
+	TrendBucketDay: {
+		expr:       "DATE_FORMAT(test_runs.created_at, '%Y-%m-%d')",
+		defaultLen: 30 * 24 * time.Hour,
+		maxLen:     366 * 24 * time.Hour,
+	},
+	// 以周一为一周的开始
+	TrendBucketWeek: {
+		expr:       "DATE_FORMAT(DATE_SUB(DATE(test_runs.created_at), INTERVAL WEEKDAY(test_runs.created_at) DAY), '%Y-%m-%d')",
+		defaultLen: 26 * 7 * 24 * time.Hour,
+		maxLen:     3 * 366 * 24 * time.Hour,
+	},
+}
+
+// PassRateTrendParams 通过率趋势查询参数，列表为空时不过滤
+type PassRateTrendParams struct {
+	ProjectIDs     []uint64
+	Branches       []string
+	TestTypes      []string
+	Bucket         string // hour / day / week，默认为 day
+	StartTime      *time.Time
+	EndTime        *time.Time
+	IncludePrivate bool // 为 true 时包含私有测试运行（管理员使用）
+}
+
+// PassRateTrendPoint 一个时间段内的测例统计
+type PassRateTrendPoint struct {
+	Bucket       string  `json:"bucket"` // 时间段的开始，如 2024-01-15（day / week）或 2024-01-15 10:00（hour）
+	Runs         int64   `json:"runs"`
+	TotalCases   int64   `json:"total_cases"`
+	PassedCases  int64   `json:"passed_cases"`
+	FailedCases  int64   `json:"failed_cases"`
+	SkippedCases int64   `json:"skipped_cases"`
+	PassRate     float64 `json:"pass_rate"`
+}
+
+// PassRateTrendSeries 一个分支和测试类型的通过率趋势，数据点按时间排序，没有测试运行的时间段不返回
+type PassRateTrendSeries struct {
+	BranchName string               `json:"branch_name"`
+	TestType   string               `json:"test_type"`
+	Points     []PassRateTrendPoint `json:"points"`
+}
+
+// PassRateTrend 通过率趋势
+type PassRateTrend struct {
+	Bucket    string                `json:"bucket"`
+	StartTime time.Time             `json:"start_time"`
+	EndTime   time.Time             `json:"end_time"`
+	Series    []PassRateTrendSeries `json:"series"`
+}
+
+// GetPassRateTrend 按时间段统计已完成（passed / failed）测试运行中各状态的测例数量和通过率
+// 使用测试运行上保存的测例统计，每个分支和测试类型一个序列，多个项目的数据合并统计；
+// 时间粒度不合法或时间范围超过该粒度允许的最大范围时返回 ErrInvalidTrendParams
+func GetPassRateTrend(c *gin.Context, params PassRateTrendParams) (*PassRateTrend, error) {
+	if params.Bucket == "" {
+		params.Bucket = TrendBucketDay
+	}
+	spec, ok := trendBuckets[params.Bucket]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown bucket '%s'", ErrInvalidTrendParams, params.Bucket)
+	}
+
+	end := time.Now()
+	if params.EndTime != nil {
+		end = *params.EndTime
+	}
+	start := end.Add(-spec.defaultLen)
+	if params.StartTime != nil {
+		start = *params.StartTime
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("%w: start_time must be earlier than end_time", ErrInvalidTrendParams)
+	}
+	if end.Sub(start) > spec.maxLen {
+		return nil, fmt.Errorf("%w: time range is too long for %s buckets", ErrInvalidTrendParams, params.Bucket)
+	}
+
+	db := getDB(c)
+	query := db.Table("test_runs").
+		Select(spec.expr+" AS bucket, test_runs.branch_name, test_runs.test_type, "+
+			"COUNT(*) AS runs, COALESCE(SUM(test_runs.total_cases), 0) AS total_cases, "+
+			"COALESCE(SUM(test_runs.passed_cases), 0) AS passed_cases, "+
+			"COALESCE(SUM(test_runs.failed_cases), 0) AS failed_cases, "+
+			"COALESCE(SUM(test_runs.skipped_cases), 0) AS skipped_cases").
+		Where("test_runs.status IN ?", []models.TestRunStatus{models.TestRunStatusPassed, models.TestRunStatusFailed}).
+		Where("test_runs.created_at >= ? AND test_runs.created_at < ?", start, end)
+
+	if !params.IncludePrivate {
+		query = query.Where("test_runs.is_public = ?", true)
+	}
+	if len(params.ProjectIDs) > 0 {
+		query = query.Where("test_runs.project_id IN ?", params.ProjectIDs)
+	}
+	if len(params.Branches) > 0 {
+		query = query.Where("test_runs.branch_name IN ?", params.Branches)
+	}
+	if len(params.TestTypes) > 0 {
+		query = query.Where("test_runs.test_type IN ?", params.TestTypes)
+	}
+
+	var rows []struct {
+		PassRateTrendPoint
+		BranchName string
+		TestType   string
+	}
+	if err := query.Group("bucket, test_runs.branch_name, test_runs.test_type").
+		Order("bucket ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get pass rate trend: %w", err)
+	}
+
+	trend := &PassRateTrend{
+		Bucket:    params.Bucket,
+		StartTime: start,
+		EndTime:   end,
+		Series:    []PassRateTrendSeries{},
+	}
+	index := make(map[[2]string]int)
+	for _, row := range rows {
+		point := row.PassRateTrendPoint
+		if point.TotalCases > 0 {
+			point.PassRate = float64(point.PassedCases) / float64(point.TotalCases) * 100.0
+		}
+
+		key := [2]string{row.BranchName, row.TestType}
+		i, ok := index[key]
+		if !ok {
+			i = len(trend.Series)
+			index[key] = i
+			trend.Series = append(trend.Series, PassRateTrendSeries{BranchName: row.BranchName, TestType: row.TestType})
+		}
+		trend.Series[i].Points = append(trend.Series[i].Points, point)
+	}
+
+	sort.SliceStable(trend.Series, func(i, j int) bool {
+		if trend.Series[i].BranchName != trend.Series[j].BranchName {
+			return trend.Series[i].BranchName < trend.Series[j].BranchName
+		}
+		return trend.Series[i].TestType < trend.Series[j].TestType
+	})
+	return trend, nil
+}
@@ -0,0 +1,5 @@
+-- 移除幂等上传相关字段
+ALTER TABLE test_runs
+DROP INDEX idx_project_idempotency_key,
+DROP COLUMN payload_hash,
+DROP COLUMN idempotency_key;
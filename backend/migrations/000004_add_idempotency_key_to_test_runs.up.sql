@@ -0,0 +1,5 @@
+-- 添加幂等上传相关字段到test_runs表
+ALTER TABLE test_runs
+ADD COLUMN idempotency_key VARCHAR(255) NULL COMMENT '上传幂等键（如CI任务ID+重试次数）' AFTER created_at,
+ADD COLUMN payload_hash VARCHAR(64) NOT NULL DEFAULT '' COMMENT '上传内容哈希，用于识别冲突的重放' AFTER idempotency_key,
+ADD UNIQUE INDEX idx_project_idempotency_key (project_id, idempotency_key);
@@ -0,0 +1,3 @@
+-- 删除测试类型相关表
+DROP TABLE IF EXISTS test_type_projects;
+DROP TABLE IF EXISTS test_types;
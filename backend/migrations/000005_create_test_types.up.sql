@@ -0,0 +1,24 @@
+-- 创建测试类型注册表
+CREATE TABLE IF NOT EXISTS test_types (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE COMMENT '测试类型名称（对应test_runs.test_type）',
+    display_name VARCHAR(255) NOT NULL COMMENT '显示名称',
+    description TEXT COMMENT '描述',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='测试类型注册表';
+
+-- 创建测试类型与项目的关联表（没有关联记录表示所有项目都可以使用）
+CREATE TABLE IF NOT EXISTS test_type_projects (
+    test_type_id BIGINT UNSIGNED NOT NULL COMMENT '测试类型ID',
+    project_id BIGINT UNSIGNED NOT NULL COMMENT '项目ID',
+    PRIMARY KEY (test_type_id, project_id),
+    INDEX idx_project_id (project_id),
+    FOREIGN KEY (test_type_id) REFERENCES test_types(id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='测试类型允许的项目';
+
+-- 插入默认测试类型
+INSERT INTO test_types (name, display_name, description)
+VALUES ('gvisor', 'gVisor', 'gVisor 系统调用测试')
+ON DUPLICATE KEY UPDATE name=name;
@@ -0,0 +1,9 @@
+-- 移除测例完整日志文件相关字段
+ALTER TABLE test_cases
+DROP COLUMN debug_log_path,
+DROP COLUMN error_log_path,
+DROP COLUMN debug_log_truncated,
+DROP COLUMN error_log_truncated;
+
+ALTER TABLE projects
+DROP COLUMN log_inline_limit;
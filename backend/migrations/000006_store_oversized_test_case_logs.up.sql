@@ -0,0 +1,10 @@
+-- 添加测例日志内联长度配置到projects表
+ALTER TABLE projects
+ADD COLUMN log_inline_limit INT UNSIGNED NOT NULL DEFAULT 2048 COMMENT '测例日志内联长度（字节），超出部分保存为文件' AFTER updated_at;
+
+-- 添加日志截断标记和完整日志文件路径到test_cases表
+ALTER TABLE test_cases
+ADD COLUMN error_log_truncated BOOLEAN NOT NULL DEFAULT FALSE COMMENT '错误日志是否被截断' AFTER created_at,
+ADD COLUMN debug_log_truncated BOOLEAN NOT NULL DEFAULT FALSE COMMENT '调试日志是否被截断' AFTER error_log_truncated,
+ADD COLUMN error_log_path VARCHAR(1000) NOT NULL DEFAULT '' COMMENT '完整错误日志文件路径' AFTER debug_log_truncated,
+ADD COLUMN debug_log_path VARCHAR(1000) NOT NULL DEFAULT '' COMMENT '完整调试日志文件路径' AFTER error_log_path;
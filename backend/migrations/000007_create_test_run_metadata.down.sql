@@ -0,0 +1,2 @@
+-- 删除测试运行元数据表
+DROP TABLE IF EXISTS test_run_metadata;
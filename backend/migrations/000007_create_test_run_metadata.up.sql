@@ -0,0 +1,11 @@
+-- 创建测试运行元数据表
+CREATE TABLE IF NOT EXISTS test_run_metadata (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    test_run_id BIGINT UNSIGNED NOT NULL COMMENT '测试运行ID',
+    meta_key VARCHAR(64) NOT NULL COMMENT '元数据键（如 runner_hostname、qemu_version）',
+    meta_value VARCHAR(255) NOT NULL COMMENT '元数据值',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_test_run_meta_key (test_run_id, meta_key),
+    INDEX idx_meta_key_value (meta_key, meta_value),
+    FOREIGN KEY (test_run_id) REFERENCES test_runs(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='测试运行元数据表';
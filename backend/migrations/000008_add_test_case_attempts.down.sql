@@ -0,0 +1,7 @@
+-- 删除测例尝试记录表和重试信息
+DROP TABLE IF EXISTS test_case_attempts;
+
+ALTER TABLE test_cases
+DROP INDEX idx_passed_on_retry,
+DROP COLUMN passed_on_retry,
+DROP COLUMN attempt_count;
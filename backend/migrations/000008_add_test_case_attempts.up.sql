@@ -0,0 +1,19 @@
+-- 添加重试信息到test_cases表
+ALTER TABLE test_cases
+ADD COLUMN attempt_count SMALLINT UNSIGNED NOT NULL DEFAULT 1 COMMENT '尝试次数' AFTER debug_log_path,
+ADD COLUMN passed_on_retry BOOLEAN NOT NULL DEFAULT FALSE COMMENT '是否在失败后重试通过' AFTER attempt_count,
+ADD INDEX idx_passed_on_retry (passed_on_retry);
+
+-- 创建测例尝试记录表
+CREATE TABLE IF NOT EXISTS test_case_attempts (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    test_case_id BIGINT UNSIGNED NOT NULL COMMENT '测例ID',
+    attempt SMALLINT UNSIGNED NOT NULL COMMENT '第几次尝试（从1开始）',
+    status ENUM('passed', 'failed', 'skipped') NOT NULL COMMENT '本次尝试的状态',
+    duration_ms INT UNSIGNED DEFAULT 0 COMMENT '执行时长（毫秒）',
+    error_log TEXT COMMENT '错误日志预览',
+    debug_log TEXT COMMENT '调试日志预览',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_test_case_id (test_case_id),
+    FOREIGN KEY (test_case_id) REFERENCES test_cases(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='测例尝试记录表';
@@ -0,0 +1,6 @@
+-- 移除测例名称层级字段
+ALTER TABLE test_cases
+DROP INDEX idx_test_run_suite,
+DROP COLUMN param,
+DROP COLUMN test_name,
+DROP COLUMN suite;
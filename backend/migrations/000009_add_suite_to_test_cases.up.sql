@@ -0,0 +1,7 @@
+-- 添加测例名称层级字段到test_cases表：[实例化前缀/]Suite.Test[/参数]
+-- 已有测例的层级使用 admin-cli -action=backfill-test-case-names 拆分，与上传时的拆分规则一致
+ALTER TABLE test_cases
+ADD COLUMN suite VARCHAR(500) NOT NULL DEFAULT '' COMMENT '测试套件（含参数化实例前缀）' AFTER name,
+ADD COLUMN test_name VARCHAR(500) NOT NULL DEFAULT '' COMMENT '测试名称' AFTER suite,
+ADD COLUMN param VARCHAR(500) NOT NULL DEFAULT '' COMMENT '参数化测例的参数' AFTER test_name,
+ADD INDEX idx_test_run_suite (test_run_id, suite);
@@ -0,0 +1,8 @@
+-- 移除全部跳过时的状态推断策略
+ALTER TABLE projects
+DROP COLUMN skipped_only_status;
+
+-- 移除 error 状态，已有记录转换为 failed
+UPDATE test_runs SET status = 'failed' WHERE status = 'error';
+ALTER TABLE test_runs
+MODIFY COLUMN status ENUM('passed', 'failed', 'running', 'cancelled') NOT NULL DEFAULT 'running' COMMENT '测试状态';
@@ -0,0 +1,7 @@
+-- 添加 error 状态（基础设施故障）到test_runs表
+ALTER TABLE test_runs
+MODIFY COLUMN status ENUM('passed', 'failed', 'running', 'cancelled', 'error') NOT NULL DEFAULT 'running' COMMENT '测试状态';
+
+-- 添加全部跳过时的状态推断策略到projects表
+ALTER TABLE projects
+ADD COLUMN skipped_only_status VARCHAR(20) NOT NULL DEFAULT 'passed' COMMENT '全部测例跳过时推断的状态（passed/failed）' AFTER log_inline_limit;
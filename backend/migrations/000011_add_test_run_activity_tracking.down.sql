@@ -0,0 +1,8 @@
+-- 移除运行超时相关字段
+ALTER TABLE projects
+DROP COLUMN run_timeout_minutes;
+
+ALTER TABLE test_runs
+DROP INDEX idx_last_activity_at,
+DROP COLUMN status_reason,
+DROP COLUMN last_activity_at;
@@ -0,0 +1,11 @@
+-- 添加活动时间和状态说明到test_runs表
+ALTER TABLE test_runs
+ADD COLUMN last_activity_at DATETIME NULL COMMENT '最近一次活动（创建、追加测例、心跳）时间' AFTER payload_hash,
+ADD COLUMN status_reason VARCHAR(255) NOT NULL DEFAULT '' COMMENT '状态说明（如自动取消的原因）' AFTER last_activity_at,
+ADD INDEX idx_last_activity_at (last_activity_at);
+
+UPDATE test_runs SET last_activity_at = COALESCE(completed_at, started_at, created_at);
+
+-- 添加运行超时时间到projects表
+ALTER TABLE projects
+ADD COLUMN run_timeout_minutes INT UNSIGNED NOT NULL DEFAULT 120 COMMENT '运行中的测试无活动超时时间（分钟）' AFTER skipped_only_status;
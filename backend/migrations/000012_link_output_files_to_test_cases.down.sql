@@ -0,0 +1,5 @@
+-- 移除test_output_files表的测例关联
+ALTER TABLE test_output_files
+DROP FOREIGN KEY fk_test_output_files_test_case,
+DROP INDEX idx_test_case_id,
+DROP COLUMN test_case_id;
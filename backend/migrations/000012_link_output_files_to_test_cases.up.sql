@@ -0,0 +1,5 @@
+-- 添加测例关联到test_output_files表
+ALTER TABLE test_output_files
+ADD COLUMN test_case_id BIGINT UNSIGNED NULL COMMENT '产生该文件的测例ID，为空时属于整个测试运行' AFTER test_run_id,
+ADD INDEX idx_test_case_id (test_case_id),
+ADD CONSTRAINT fk_test_output_files_test_case FOREIGN KEY (test_case_id) REFERENCES test_cases(id) ON DELETE SET NULL;
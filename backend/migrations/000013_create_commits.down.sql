@@ -0,0 +1,2 @@
+-- 删除提交信息表
+DROP TABLE IF EXISTS commits;
@@ -0,0 +1,24 @@
+-- 创建提交信息表，同一个提交的测试运行共享一条记录
+CREATE TABLE IF NOT EXISTS commits (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    sha VARCHAR(40) NOT NULL COMMENT '提交哈希',
+    short_sha VARCHAR(10) NOT NULL COMMENT '提交哈希前10位',
+    author VARCHAR(255) NOT NULL DEFAULT '' COMMENT '提交作者',
+    title VARCHAR(500) NOT NULL DEFAULT '' COMMENT '提交信息的第一行',
+    committed_at DATETIME NULL COMMENT '提交时间',
+    parent_shas VARCHAR(1000) NOT NULL DEFAULT '' COMMENT '父提交哈希（逗号分隔）',
+    pr_number INT UNSIGNED NULL COMMENT 'PR编号',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_sha (sha),
+    INDEX idx_short_sha (short_sha),
+    INDEX idx_author (author),
+    INDEX idx_committed_at (committed_at),
+    INDEX idx_pr_number (pr_number)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='提交信息表';
+
+-- 为已有的测试运行创建提交记录
+INSERT IGNORE INTO commits (sha, short_sha, created_at, updated_at)
+SELECT commit_id, MAX(commit_short_id), MIN(created_at), NOW()
+FROM test_runs
+GROUP BY commit_id;
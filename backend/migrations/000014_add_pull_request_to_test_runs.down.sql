@@ -0,0 +1,5 @@
+-- 移除test_runs表的PR编号和合并基点
+ALTER TABLE test_runs
+DROP INDEX idx_pr_number,
+DROP COLUMN base_commit_id,
+DROP COLUMN pr_number;
@@ -0,0 +1,5 @@
+-- 添加PR编号和合并基点到test_runs表
+ALTER TABLE test_runs
+ADD COLUMN pr_number INT UNSIGNED NULL COMMENT 'PR编号' AFTER status_reason,
+ADD COLUMN base_commit_id VARCHAR(40) NOT NULL DEFAULT '' COMMENT 'PR的合并基点（master上的提交）' AFTER pr_number,
+ADD INDEX idx_pr_number (pr_number);
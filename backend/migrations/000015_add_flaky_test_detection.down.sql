@@ -0,0 +1,5 @@
+-- 删除不稳定测例表和统计窗口
+DROP TABLE IF EXISTS flaky_test_cases;
+
+ALTER TABLE projects
+DROP COLUMN flaky_window_runs;
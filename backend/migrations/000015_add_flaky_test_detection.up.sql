@@ -0,0 +1,23 @@
+-- 添加不稳定测例统计窗口到projects表
+ALTER TABLE projects
+ADD COLUMN flaky_window_runs INT UNSIGNED NOT NULL DEFAULT 30 COMMENT '计算测例不稳定度时使用的最近测试运行数量' AFTER run_timeout_minutes;
+
+-- 创建不稳定测例表，按项目、分支和测试类型统计，测试运行结束时重新计算
+CREATE TABLE IF NOT EXISTS flaky_test_cases (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    project_id BIGINT UNSIGNED NOT NULL COMMENT '项目ID',
+    branch_name VARCHAR(255) NOT NULL COMMENT '分支名称',
+    test_type VARCHAR(50) NOT NULL COMMENT '测试类型',
+    name VARCHAR(500) NOT NULL COMMENT '测例名称',
+    score DOUBLE NOT NULL DEFAULT 0 COMMENT '不稳定度（0~1）',
+    is_flaky BOOLEAN NOT NULL DEFAULT FALSE COMMENT '是否被标记为不稳定',
+    runs INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '窗口内有结果的运行次数',
+    flips INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '相邻结果在通过和失败之间切换的次数',
+    same_commit_flips INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '同一提交上既通过又失败的次数（包括重试后通过）',
+    last_failed_run_id BIGINT UNSIGNED NULL COMMENT '最近一次失败的测试运行ID',
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_flaky_scope (project_id, branch_name, test_type),
+    INDEX idx_name (name),
+    INDEX idx_score (score),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='不稳定测例表';
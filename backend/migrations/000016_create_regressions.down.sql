@@ -0,0 +1,2 @@
+-- 删除回归表
+DROP TABLE IF EXISTS regressions;
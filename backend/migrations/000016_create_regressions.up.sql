@@ -0,0 +1,24 @@
+-- 创建回归表，master 分支上通过的测例开始失败时记录提交范围，测例重新通过时自动关闭
+CREATE TABLE IF NOT EXISTS regressions (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    project_id BIGINT UNSIGNED NOT NULL COMMENT '项目ID',
+    test_type VARCHAR(50) NOT NULL COMMENT '测试类型',
+    name VARCHAR(500) NOT NULL COMMENT '测例名称',
+    status ENUM('open', 'closed') NOT NULL DEFAULT 'open' COMMENT '回归状态',
+    last_good_run_id BIGINT UNSIGNED NULL COMMENT '最后一次通过的测试运行ID',
+    last_good_commit_id VARCHAR(40) NOT NULL COMMENT '最后一次通过的提交',
+    first_bad_run_id BIGINT UNSIGNED NULL COMMENT '第一次失败的测试运行ID',
+    first_bad_commit_id VARCHAR(40) NOT NULL COMMENT '第一次失败的提交',
+    fixed_run_id BIGINT UNSIGNED NULL COMMENT '测例重新通过的测试运行ID',
+    fixed_commit_id VARCHAR(40) NOT NULL DEFAULT '' COMMENT '测例重新通过的提交',
+    opened_at DATETIME NOT NULL COMMENT '打开时间',
+    closed_at DATETIME NULL COMMENT '关闭时间',
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_regression_scope (project_id, test_type, status),
+    INDEX idx_name (name),
+    INDEX idx_opened_at (opened_at),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (last_good_run_id) REFERENCES test_runs(id) ON DELETE SET NULL,
+    FOREIGN KEY (first_bad_run_id) REFERENCES test_runs(id) ON DELETE SET NULL,
+    FOREIGN KEY (fixed_run_id) REFERENCES test_runs(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='测例回归表';
@@ -0,0 +1,3 @@
+-- 删除测例变慢的耗时倍数
+ALTER TABLE projects
+DROP COLUMN slowdown_factor;
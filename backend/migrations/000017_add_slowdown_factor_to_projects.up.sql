@@ -0,0 +1,3 @@
+-- 添加测例变慢的耗时倍数到projects表
+ALTER TABLE projects
+ADD COLUMN slowdown_factor DOUBLE NOT NULL DEFAULT 2 COMMENT '测例耗时达到历史耗时中位数的该倍数时视为变慢' AFTER flaky_window_runs;
@@ -0,0 +1,8 @@
+-- 删除测试运行的测例统计
+ALTER TABLE test_runs
+DROP COLUMN total_duration_ms,
+DROP COLUMN passed_on_retry_cases,
+DROP COLUMN skipped_cases,
+DROP COLUMN failed_cases,
+DROP COLUMN passed_cases,
+DROP COLUMN total_cases;
@@ -0,0 +1,28 @@
+-- 添加测例统计到test_runs表，写入测例和测试运行结束时更新
+ALTER TABLE test_runs
+ADD COLUMN total_cases INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '测例总数' AFTER base_commit_id,
+ADD COLUMN passed_cases INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '通过的测例数' AFTER total_cases,
+ADD COLUMN failed_cases INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '失败的测例数' AFTER passed_cases,
+ADD COLUMN skipped_cases INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '跳过的测例数' AFTER failed_cases,
+ADD COLUMN passed_on_retry_cases INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '重试后通过的测例数（包含在通过数中）' AFTER skipped_cases,
+ADD COLUMN total_duration_ms BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '测例总耗时（毫秒）' AFTER passed_on_retry_cases;
+
+-- 根据已有的测例生成统计
+UPDATE test_runs
+JOIN (
+    SELECT test_run_id,
+        COUNT(*) AS total_cases,
+        SUM(status = 'passed') AS passed_cases,
+        SUM(status = 'failed') AS failed_cases,
+        SUM(status = 'skipped') AS skipped_cases,
+        SUM(passed_on_retry) AS passed_on_retry_cases,
+        COALESCE(SUM(duration_ms), 0) AS total_duration_ms
+    FROM test_cases
+    GROUP BY test_run_id
+) AS summary ON summary.test_run_id = test_runs.id
+SET test_runs.total_cases = summary.total_cases,
+    test_runs.passed_cases = summary.passed_cases,
+    test_runs.failed_cases = summary.failed_cases,
+    test_runs.skipped_cases = summary.skipped_cases,
+    test_runs.passed_on_retry_cases = summary.passed_on_retry_cases,
+    test_runs.total_duration_ms = summary.total_duration_ms;
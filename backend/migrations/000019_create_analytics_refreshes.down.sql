@@ -0,0 +1,2 @@
+-- 删除分析数据更新队列表
+DROP TABLE IF EXISTS analytics_refreshes;
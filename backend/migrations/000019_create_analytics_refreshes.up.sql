@@ -0,0 +1,11 @@
+-- 创建分析数据更新队列表，测试运行结束、可见性变化或删除时登记需要重新计算不稳定测例等数据的范围
+CREATE TABLE IF NOT EXISTS analytics_refreshes (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    project_id BIGINT UNSIGNED NOT NULL COMMENT '项目ID',
+    branch_name VARCHAR(255) NOT NULL COMMENT '分支',
+    test_type VARCHAR(50) NOT NULL COMMENT '测试类型',
+    version BIGINT UNSIGNED NOT NULL DEFAULT 1 COMMENT '登记次数，处理期间再次登记时增加',
+    requested_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '最近一次登记时间',
+    UNIQUE KEY idx_analytics_scope (project_id, branch_name, test_type),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='分析数据更新队列表';
@@ -0,0 +1,4 @@
+-- 删除回归检测进度表
+ALTER TABLE regressions DROP COLUMN recheck;
+
+DROP TABLE IF EXISTS regression_cursors;
@@ -0,0 +1,14 @@
+-- 创建回归检测进度表，记录每个项目和测试类型在 master 上已检测到的最后一次测试运行
+CREATE TABLE IF NOT EXISTS regression_cursors (
+    project_id BIGINT UNSIGNED NOT NULL COMMENT '项目ID',
+    test_type VARCHAR(50) NOT NULL COMMENT '测试类型',
+    last_run_id BIGINT UNSIGNED NOT NULL COMMENT '已检测的最后一次测试运行ID（运行被删除后仍保留）',
+    last_run_created_at DATETIME NOT NULL COMMENT '已检测的最后一次测试运行的创建时间',
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, test_type),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='回归检测进度表';
+
+-- 回归引用的测试运行被设为私有或删除后，需要根据当前的公开历史重新检查
+ALTER TABLE regressions
+ADD COLUMN recheck BOOLEAN NOT NULL DEFAULT FALSE COMMENT '是否需要重新检查' AFTER fixed_commit_id;